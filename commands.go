@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/syeero7/boot-chirpy/internal/database"
+)
+
+const commandUsage = "usage: chirpy grant-admin <email>"
+
+// runCommand runs a maintenance command given on the command line instead of
+// starting the server.
+func runCommand(ctx context.Context, db *database.Queries, args []string) error {
+	switch args[0] {
+	case "grant-admin":
+		if len(args) != 2 {
+			return errors.New(commandUsage)
+		}
+
+		return grantAdmin(ctx, db, args[1])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], commandUsage)
+	}
+}
+
+// grantAdmin makes an existing account an admin. It is how the first admin is
+// created; after that admins can promote others through the API.
+func grantAdmin(ctx context.Context, db *database.Queries, email string) error {
+	user, err := db.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no account with email %s", email)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.DeletedAt.Valid {
+		return fmt.Errorf("the account with email %s is deleted", email)
+	}
+
+	if user.Role == roleAdmin {
+		fmt.Printf("%s is already an admin\n", user.Email)
+		return nil
+	}
+
+	if _, err := db.SetUserRole(ctx, database.SetUserRoleParams{ID: user.ID, Role: roleAdmin}); err != nil {
+		return err
+	}
+
+	err = db.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		Action: "user.role_changed",
+		Detail: fmt.Sprintf("%s to %s from the command line", user.Role, roleAdmin),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now an admin\n", user.Email)
+	return nil
+}
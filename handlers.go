@@ -1,25 +1,79 @@
 package main
 
 import (
+	"archive/zip"
+	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"sort"
+	"net/url"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/syeero7/boot-chirpy/internal/auth"
+	"github.com/syeero7/boot-chirpy/internal/backoff"
 	"github.com/syeero7/boot-chirpy/internal/database"
+	"github.com/syeero7/boot-chirpy/internal/mailer"
+	"github.com/syeero7/boot-chirpy/internal/moderation"
+	"github.com/syeero7/boot-chirpy/internal/oauth"
+	"github.com/syeero7/boot-chirpy/internal/pagination"
+	"github.com/syeero7/boot-chirpy/internal/parse"
+	"github.com/syeero7/boot-chirpy/internal/ratelimit"
+	"github.com/syeero7/boot-chirpy/internal/totp"
+	"github.com/syeero7/boot-chirpy/internal/validate"
+)
+
+const (
+	refreshTokenDuration           = 60 * 24 * time.Hour
+	emailVerificationTokenDuration = 24 * time.Hour
+	passwordResetTokenDuration     = 1 * time.Hour
+	accountDeletionGracePeriod     = 30 * 24 * time.Hour
+	twoFactorChallengeDuration     = 5 * time.Minute
+	recoveryCodeCount              = 10
+	totpIssuer                     = "Chirpy"
+	maxTokenNameLength             = 100
+	maxTokenLifetimeDays           = 365
+	maxRedirectURIs                = 10
+	authorizationCodeDuration      = 1 * time.Minute
+	maxModerationListNameLength    = 100
+	maxReportDetailsLength         = 500
+	maxSuspensionReasonLength      = 500
+	loginFailureWindow             = 24 * time.Hour
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "other"}
+
+// Failed logins slow down and eventually lock out both the email address
+// being guessed and the client guessing. An IP gets more room, since many
+// users can share one.
+var (
+	accountLoginPolicy = backoff.Policy{Free: 3, Base: 1 * time.Second, Max: 5 * time.Minute, LockoutAfter: 10, LockoutDuration: 15 * time.Minute}
+	ipLoginPolicy      = backoff.Policy{Free: 20, Base: 1 * time.Second, Max: 5 * time.Minute, LockoutAfter: 100, LockoutDuration: 1 * time.Hour}
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	conn           *sql.DB
 	platform       string
 	jwtSecret      string
+	jwtKeys        *auth.KeySet
 	polkaKey       string
+	mailer         mailer.Mailer
+	limiter        ratelimit.Store
+
+	// moderationFilter is rebuilt whenever the lists change; moderationFileLists
+	// are the read-only lists from MODERATION_FILE.
+	moderationFilter    atomic.Pointer[moderation.Filter]
+	moderationFileLists []moderation.List
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -35,6 +89,8 @@ func (cfg *apiConfig) getRequestCount(w http.ResponseWriter, _ *http.Request) {
 	w.Write(fmt.Appendf(nil, "<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %d times!</p></body></html>", cfg.fileserverHits.Load()))
 }
 
+// resetServer deletes every user. Even admins can only do this on the dev
+// platform.
 func (cfg *apiConfig) resetServer(w http.ResponseWriter, req *http.Request) {
 	if cfg.platform != "dev" {
 		respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
@@ -52,15 +108,36 @@ func (cfg *apiConfig) resetServer(w http.ResponseWriter, req *http.Request) {
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, req *http.Request) {
 	type reqParams struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string  `json:"email"`
+		Password string  `json:"password"`
+		Handle   *string `json:"handle"`
 	}
 
 	decoder := json.NewDecoder(req.Body)
 	params := reqParams{}
 
 	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	if err := validate.Email(params.Email); err != nil {
+		fields["email"] = err.Error()
+	}
+
+	if err := validate.Password(params.Password); err != nil {
+		fields["password"] = err.Error()
+	}
+
+	if params.Handle != nil {
+		if err := validate.Handle(*params.Handle); err != nil {
+			fields["handle"] = err.Error()
+		}
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
 		return
 	}
 
@@ -70,195 +147,223 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userData := database.CreateUserParams{Email: params.Email, HashedPassword: hash}
+	userData := database.CreateUserParams{
+		Email:          params.Email,
+		HashedPassword: hash,
+		Handle:         nullString(params.Handle),
+	}
+
 	user, err := cfg.db.CreateUser(req.Context(), userData)
 	if err != nil {
+		if msg, ok := userConflict(err); ok {
+			respondWithError(w, http.StatusConflict, msg)
+			return
+		}
+
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, &user)
+	// the account exists either way, the user can ask for another email
+	if err := cfg.sendVerificationEmail(req.Context(), user); err != nil {
+		log.Printf("failed to send verification email to user %s: %v", user.ID, err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, newUserRes(user))
 }
 
-func (cfg *apiConfig) loginUser(w http.ResponseWriter, req *http.Request) {
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeEmailVerificationToken(user.ID, user.Email, cfg.jwtSecret, emailVerificationTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Welcome to Chirpy!\n\n" +
+			"Send the token below to POST /api/users/verify to verify your email address. It expires in 24 hours.\n\n" +
+			token + "\n",
+	}
+
+	return cfg.mailer.Send(ctx, msg)
+}
+
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, req *http.Request) {
 	type reqParams struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Token string `json:"token"`
 	}
 
 	decoder := json.NewDecoder(req.Body)
 	params := reqParams{}
-
 	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
 
-	user, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
+	userID, email, err := auth.ValidateEmailVerificationToken(params.Token, cfg.jwtSecret)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
 		return
 	}
 
-	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
-	if err != nil || !match {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
-		return
+	verifyData := database.VerifyUserEmailParams{
+		ID:    userID,
+		Email: email,
 	}
 
-	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, 1*time.Hour)
+	// no rows means the account is gone or its email changed since the token was sent
+	verified, err := cfg.db.VerifyUserEmail(req.Context(), verifyData)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	if verified == 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
 		return
 	}
 
-	refreshTokenData := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().UTC().Add(24 * time.Hour * 60),
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) resendVerificationEmail(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified")
+		return
 	}
 
-	if err := cfg.db.CreateRefreshToken(req.Context(), refreshTokenData); err != nil {
+	if err := cfg.sendVerificationEmail(req.Context(), user); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	type ResData struct {
-		ID           uuid.UUID `json:"id"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) forgotPassword(w http.ResponseWriter, req *http.Request) {
+	type reqParams struct {
+		Email string `json:"email"`
 	}
 
-	data := ResData{
-		ID:           user.ID,
-		Email:        user.Email,
-		Token:        token,
-		RefreshToken: refreshToken,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		IsChirpyRed:  user.IsChirpyRed,
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, &data)
+	// the lookup and email happen off the request so neither the status nor
+	// the response time reveals whether the address has an account
+	go func(ctx context.Context, email string) {
+		if err := cfg.sendPasswordResetEmail(ctx, email); err != nil {
+			log.Printf("failed to send password reset email: %v", err)
+		}
+	}(context.WithoutCancel(req.Context()), params.Email)
+
+	w.WriteHeader(http.StatusAccepted)
 }
 
-func (cfg *apiConfig) updateUserData(w http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
-		return
+func (cfg *apiConfig) sendPasswordResetEmail(ctx context.Context, email string) error {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
-		return
+		return err
 	}
 
-	type reqParams struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	token, err := auth.MakeToken()
+	if err != nil {
+		return err
 	}
 
-	decoder := json.NewDecoder(req.Body)
-	params := reqParams{}
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-		return
+	// only the most recent reset email stays usable
+	if err := cfg.db.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+		return err
 	}
 
-	hash, err := auth.HashPassword(params.Password)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-		return
+	tokenData := database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTokenDuration),
 	}
 
-	userData := database.UpdateUserParams{
-		ID:             userID,
-		Email:          params.Email,
-		HashedPassword: hash,
-		UpdatedAt:      time.Now(),
+	if err := cfg.db.CreatePasswordResetToken(ctx, tokenData); err != nil {
+		return err
 	}
 
-	user, err := cfg.db.UpdateUser(req.Context(), userData)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
-		return
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password for your Chirpy account. If it wasn't you, ignore this email.\n\n" +
+			"Send the token below with your new password to POST /api/password/reset. It expires in 1 hour and can be used once.\n\n" +
+			token + "\n",
 	}
 
-	type resData struct {
-		ID          uuid.UUID `json:"id"`
-		Email       string    `json:"email"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
-	}
+	return cfg.mailer.Send(ctx, msg)
+}
 
-	data := resData{
-		ID:          user.ID,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		IsChirpyRed: user.IsChirpyRed,
+func (cfg *apiConfig) resetPassword(w http.ResponseWriter, req *http.Request) {
+	type reqParams struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
 	}
 
-	respondWithJSON(w, http.StatusOK, &data)
-}
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
 
-func (cfg *apiConfig) createRefreshToken(w http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	if err := validate.Password(params.Password); err != nil {
+		respondWithValidationErrors(w, map[string]string{"password": err.Error()})
 		return
 	}
 
-	user, err := cfg.db.GetUserByRefreshToken(req.Context(), token)
+	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	str, err := auth.MakeJWT(user.ID, cfg.jwtSecret, 1*time.Hour)
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
+	defer tx.Rollback()
 
-	type resData struct {
-		Token string `json:"token"`
+	qtx := cfg.db.WithTx(tx)
+	userID, err := qtx.ConsumePasswordResetToken(req.Context(), database.ConsumePasswordResetTokenParams{
+		Now:       time.Now().UTC(),
+		TokenHash: auth.HashToken(params.Token),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
 	}
 
-	data := resData{
-		Token: str,
+	userData := database.UpdateUserParams{
+		ID:             userID,
+		HashedPassword: sql.NullString{String: hash, Valid: true},
 	}
 
-	respondWithJSON(w, http.StatusOK, &data)
-}
-
-func (cfg *apiConfig) revokeRefreshToken(w http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	if _, err := qtx.UpdateUser(req.Context(), userData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	revokeTokenData := database.RevokeRefreshTokenParams{
-		Token:     token,
-		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
-		UpdatedAt: time.Now(),
+	if err := qtx.RevokeUserRefreshTokens(req.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
 	}
 
-	if err := cfg.db.RevokeRefreshToken(req.Context(), revokeTokenData); err != nil {
+	if err := tx.Commit(); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
@@ -266,180 +371,3730 @@ func (cfg *apiConfig) revokeRefreshToken(w http.ResponseWriter, req *http.Reques
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (cfg *apiConfig) createChirp(w http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
-		return
+// requireVerifiedEmail rejects users who have not verified their email
+// address yet. It reports whether the request may continue.
+func requireVerifiedEmail(w http.ResponseWriter, user database.User) bool {
+	if !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before posting")
+		return false
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
-		return
-	}
+	return true
+}
 
+func (cfg *apiConfig) loginUser(w http.ResponseWriter, req *http.Request) {
 	type reqParams struct {
-		Body string `json:"body"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
 	}
 
 	decoder := json.NewDecoder(req.Body)
 	params := reqParams{}
+
 	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
 
-	if limit := 140; len(params.Body) > limit {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
+	throttle := newLoginThrottle(req, params.Email)
+	attempt, ok := cfg.reserveLoginAttempt(w, req, throttle)
+	if !ok {
 		return
 	}
 
-	chirpData := database.CreateChirpParams{UserID: userID, Body: filterProfanity(params.Body)}
-	chirp, err := cfg.db.CreateChirp(req.Context(), chirpData)
-	if err != nil {
+	match := false
+	userID := uuid.NullUUID{}
+	user, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		auth.CheckDummyPassword(params.Password)
+	case err != nil:
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
+	default:
+		userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		match, _ = auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	}
 
-	respondWithJSON(w, http.StatusCreated, &chirp)
-}
-
-func (cfg *apiConfig) getChirps(w http.ResponseWriter, req *http.Request) {
-	chirps := []database.Chirp{}
-
-	if s := req.URL.Query().Get("author_id"); len(s) > 0 {
-		authorID, err := uuid.Parse(s)
-		if err != nil {
+	if !match {
+		if err := cfg.recordLoginFailure(req, throttle, attempt, userID); err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 			return
 		}
 
-		tmp, err := cfg.db.GetChirpsByAuthorID(req.Context(), authorID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-			return
-		}
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
 
-		chirps = tmp
+	if err := cfg.releaseLoginAttempt(req.Context(), throttle, attempt); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
 
-	} else {
-		tmp, err := cfg.db.GetChirps(req.Context())
+	if user.TotpEnabledAt.Valid {
+		challenge, err := auth.MakeTwoFactorChallengeToken(user.ID, cfg.jwtSecret, twoFactorChallengeDuration)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 			return
 		}
 
-		chirps = tmp
-	}
+		type resData struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+		}
 
-	sortOrder := "asc"
-	if s := req.URL.Query().Get("author_id"); s == "desc" {
-		sortOrder = "desc"
+		data := resData{TwoFactorRequired: true, ChallengeToken: challenge}
+		respondWithJSON(w, http.StatusOK, &data)
+		return
 	}
 
-	sort.Slice(chirps, func(i, j int) bool {
-		if sortOrder == "desc" {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
-		} else {
-			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
-		}
-	})
-
-	respondWithJSON(w, http.StatusOK, &chirps)
+	cfg.completeLogin(w, req, user)
 }
 
-func (cfg *apiConfig) getChirpByID(w http.ResponseWriter, req *http.Request) {
-	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-		return
+func (cfg *apiConfig) loginTwoFactor(w http.ResponseWriter, req *http.Request) {
+	type reqParams struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
 	}
 
-	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
-	if err != nil {
-		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, &chirp)
-}
-
-func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateTwoFactorChallengeToken(params.ChallengeToken, cfg.jwtSecret)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	user, err := cfg.db.GetUserForLogin(req.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
 		return
 	}
 
-	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	// codes are only six digits, so guesses count against the same limits as
+	// passwords do
+	throttle := newLoginThrottle(req, user.Email)
+	attempt, ok := cfg.reserveLoginAttempt(w, req, throttle)
+	if !ok {
 		return
 	}
 
-	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
+	ok, err = cfg.checkSecondFactor(req.Context(), user, params.Code, params.RecoveryCode)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	if chirp.UserID != userID {
-		respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	if !ok {
+		if err := cfg.recordLoginFailure(req, throttle, attempt, uuid.NullUUID{UUID: user.ID, Valid: true}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
 		return
 	}
 
-	if err := cfg.db.DeleteChirp(req.Context(), chirpID); err != nil {
+	if err := cfg.releaseLoginAttempt(req.Context(), throttle, attempt); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	cfg.completeLogin(w, req, user)
 }
 
-func (cfg *apiConfig) upgradeChirpyMembership(w http.ResponseWriter, req *http.Request) {
-	if key, err := auth.GetAPIKey(req.Header); err != nil || key != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
-		return
-	}
+// loginThrottle names the counters a login attempt is checked against: the
+// email address, whether or not an account has it, and the client's IP.
+type loginThrottle struct {
+	accountKey string
+	ipKey      string
+}
 
-	type reqParams struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+func newLoginThrottle(req *http.Request, email string) loginThrottle {
+	return loginThrottle{
+		accountKey: "account:" + strings.ToLower(email),
+		ipKey:      "ip:" + clientIP(req),
 	}
+}
 
-	decoder := json.NewDecoder(req.Body)
-	params := reqParams{}
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-		return
-	}
+type loginCounter struct {
+	key    string
+	action string
+	policy backoff.Policy
+}
 
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
+func (t loginThrottle) counters() []loginCounter {
+	// always locked in this order, so concurrent attempts can't deadlock
+	return []loginCounter{
+		{t.accountKey, "login.account_locked", accountLoginPolicy},
+		{t.ipKey, "login.ip_locked", ipLoginPolicy},
 	}
+}
 
-	userID, err := uuid.Parse(params.Data.UserID)
+// loginAttempt is an attempt counted against each counter, keyed like
+// loginThrottle, before its password or code was checked.
+type loginAttempt struct {
+	at       time.Time
+	counters map[string]reservedCounter
+}
+
+type reservedCounter struct {
+	// failures includes the attempt itself.
+	failures int
+	// previousFailureAt is put back if the attempt turns out to be right.
+	previousFailureAt time.Time
+}
+
+// reserveLoginAttempt counts an attempt as a failure against both counters
+// before any password or code is checked. Concurrent attempts queue up on the
+// counters' row locks, so a burst of guesses can't all slip in under the same
+// count. It responds with 429 and reports false while either counter is
+// backing off or locked out, so a correct guess during a lockout fails too.
+func (cfg *apiConfig) reserveLoginAttempt(w http.ResponseWriter, req *http.Request, throttle loginThrottle) (loginAttempt, bool) {
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
-		return
-	}
-
-	chirpyData := database.SetUserChirpyRedParams{
-		ID:          userID,
-		IsChirpyRed: true,
+		return loginAttempt{}, false
 	}
+	defer tx.Rollback()
 
-	if err := cfg.db.SetUserChirpyRed(req.Context(), chirpyData); err != nil {
+	qtx := cfg.db.WithTx(tx)
+	// truncated to what the column stores, so releasing the attempt can find
+	// its timestamp again
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	forgetBefore := now.Add(-loginFailureWindow)
+	attempt := loginAttempt{at: now, counters: map[string]reservedCounter{}}
+	wait := time.Duration(0)
+	for _, c := range throttle.counters() {
+		counter, err := qtx.LockLoginThrottle(req.Context(), database.LockLoginThrottleParams{Key: c.key, LastFailureAt: now})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return loginAttempt{}, false
+		}
+
+		failures := int(counter.Failures)
+		if counter.LastFailureAt.Before(forgetBefore) {
+			failures = 0
+		}
+
+		wait = max(wait, c.policy.RetryAfter(failures, counter.LastFailureAt, now))
+		attempt.counters[c.key] = reservedCounter{failures: failures + 1, previousFailureAt: counter.LastFailureAt}
+	}
+
+	if wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(math.Ceil(wait.Seconds())))
+		respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+		return loginAttempt{}, false
+	}
+
+	for key, counter := range attempt.counters {
+		err := qtx.SetLoginFailures(req.Context(), database.SetLoginFailuresParams{
+			Key:           key,
+			Failures:      int32(counter.failures),
+			LastFailureAt: now,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return loginAttempt{}, false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return loginAttempt{}, false
+	}
+
+	return attempt, true
+}
+
+// releaseLoginAttempt takes back a reserved attempt whose password or code
+// was right.
+func (cfg *apiConfig) releaseLoginAttempt(ctx context.Context, throttle loginThrottle, attempt loginAttempt) error {
+	for _, c := range throttle.counters() {
+		err := cfg.db.ReleaseLoginAttempt(ctx, database.ReleaseLoginAttemptParams{
+			ReservedAt:        attempt.at,
+			PreviousFailureAt: attempt.counters[c.key].previousFailureAt,
+			Key:               c.key,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure writes an audit entry for each counter that a failed
+// attempt has locked out. The failure itself was counted when the attempt was
+// reserved.
+func (cfg *apiConfig) recordLoginFailure(req *http.Request, throttle loginThrottle, attempt loginAttempt, userID uuid.NullUUID) error {
+	for _, c := range throttle.counters() {
+		failures := attempt.counters[c.key].failures
+		if !c.policy.LockedOut(failures) {
+			continue
+		}
+
+		err := cfg.db.CreateAuditLogEntry(req.Context(), database.CreateAuditLogEntryParams{
+			UserID:    userID,
+			Action:    c.action,
+			IpAddress: clientIP(req),
+			Detail:    fmt.Sprintf("locked for %s after %d failed attempts", c.policy.LockoutDuration, failures),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeLogin starts a session for a user who has passed every login step.
+// It also forgets the account's failed attempts, but not the client IP's.
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, req *http.Request, user database.User) {
+	if userSuspended(user) {
+		respondWithError(w, http.StatusForbidden, "Account suspended")
+		return
+	}
+
+	if err := cfg.db.ClearLoginFailures(req.Context(), newLoginThrottle(req, user.Email).accountKey); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// logging in during the deletion grace period cancels the deletion
+	if user.DeletedAt.Valid {
+		if err := cfg.db.RestoreUser(req.Context(), user.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		user.DeletedAt = sql.NullTime{}
+	}
+
+	token, err := auth.MakeJWT(user.ID, auth.AllScopes, cfg.jwtKeys, 1*time.Hour)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	refreshToken, err := issueRefreshToken(req, cfg.db, user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type ResData struct {
+		userRes
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	data := ResData{
+		userRes:      newUserRes(user),
+		Token:        token,
+		RefreshToken: refreshToken,
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Each is single use: a TOTP code is refused once its time step, or a later
+// one, has been accepted.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code, recoveryCode string) (bool, error) {
+	if !user.TotpEnabledAt.Valid {
+		return false, nil
+	}
+
+	if code != "" {
+		step, ok := totp.Validate(code, user.TotpSecret.String, time.Now().UTC())
+		if !ok {
+			return false, nil
+		}
+
+		rows, err := cfg.db.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{Step: step, ID: user.ID})
+		return rows == 1, err
+	}
+
+	if recoveryCode != "" {
+		rows, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(totp.NormalizeRecoveryCode(recoveryCode)),
+		})
+		return rows == 1, err
+	}
+
+	return false, nil
+}
+
+// issueRefreshToken starts a new session for userID.
+func issueRefreshToken(req *http.Request, q *database.Queries, userID uuid.UUID) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshTokenData := database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenDuration),
+		FamilyID:  uuid.New(),
+		UserAgent: req.UserAgent(),
+		IpAddress: clientIP(req),
+	}
+
+	if err := q.CreateRefreshToken(req.Context(), refreshTokenData); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+func (cfg *apiConfig) enrollTwoFactor(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	type reqParams struct {
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusForbidden, "Incorrect password")
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = cfg.db.SetUserTOTPSecret(req.Context(), database.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	data := resData{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Email, secret),
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+func (cfg *apiConfig) confirmTwoFactor(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	type reqParams struct {
+		Code string `json:"code"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Two-factor enrollment has not been started")
+		return
+	}
+
+	step, ok := totp.Validate(params.Code, user.TotpSecret.String, time.Now().UTC())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.EnableUserTOTP(req.Context(), database.EnableUserTOTPParams{
+		ID:           user.ID,
+		TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(req.Context(), qtx, user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	respondWithJSON(w, http.StatusOK, &resData{RecoveryCodes: codes})
+}
+
+func (cfg *apiConfig) regenerateRecoveryCodes(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	type reqParams struct {
+		Code string `json:"code"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(req.Context(), user, params.Code, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(req.Context(), cfg.db.WithTx(tx), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	respondWithJSON(w, http.StatusOK, &resData{RecoveryCodes: codes})
+}
+
+// replaceRecoveryCodes invalidates a user's recovery codes and returns a new
+// set. Only hashes are stored, so this is the one time they can be shown.
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID) ([]string, error) {
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashToken(totp.NormalizeRecoveryCode(code)))
+	}
+
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	err = q.CreateRecoveryCodes(ctx, database.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (cfg *apiConfig) disableTwoFactor(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	type reqParams struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusForbidden, "Incorrect password")
+		return
+	}
+
+	// an enrollment that was never confirmed can be abandoned with just the password
+	if user.TotpEnabledAt.Valid {
+		ok, err := cfg.checkSecondFactor(req.Context(), user, params.Code, params.RecoveryCode)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+			return
+		}
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	if err := qtx.DisableUserTOTP(req.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := qtx.DeleteRecoveryCodes(req.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userUpdate is the body of an account update. Omitted fields are left
+// unchanged.
+type userUpdate struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword *string `json:"current_password"`
+	Handle          *string `json:"handle"`
+	DisplayName     *string `json:"display_name"`
+	Bio             *string `json:"bio"`
+}
+
+func (cfg *apiConfig) updateUserData(w http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(req.Body)
+	params := userUpdate{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	cfg.applyUserUpdate(w, req, params, true)
+}
+
+// replaceUserData serves the deprecated PUT /api/users. Its clients change
+// email and password without sending the current password, so the route
+// only accepts login access tokens, which the account scope limits it to.
+func (cfg *apiConfig) replaceUserData(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", `</api/users/me>; rel="successor-version"`)
+
+	decoder := json.NewDecoder(req.Body)
+	params := userUpdate{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	cfg.applyUserUpdate(w, req, params, false)
+}
+
+// applyUserUpdate validates and saves an account update. Changing the email
+// or password needs the current password when confirm is set, or whenever
+// one is sent.
+func (cfg *apiConfig) applyUserUpdate(w http.ResponseWriter, req *http.Request, params userUpdate, confirm bool) {
+	userID := requestUser(req).ID
+
+	fields := map[string]string{}
+	check := func(field string, value *string, fn func(string) error) {
+		if value == nil {
+			return
+		}
+
+		if err := fn(*value); err != nil {
+			fields[field] = err.Error()
+		}
+	}
+
+	check("email", params.Email, validate.Email)
+	check("password", params.Password, validate.Password)
+	check("handle", params.Handle, validate.Handle)
+	check("display_name", params.DisplayName, validate.DisplayName)
+	check("bio", params.Bio, validate.Bio)
+
+	sensitive := params.Email != nil || params.Password != nil
+	if sensitive && confirm && params.CurrentPassword == nil {
+		fields["current_password"] = "is required to change email or password"
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	if sensitive && params.CurrentPassword != nil {
+		user := requestUser(req)
+
+		match, err := auth.CheckPasswordHash(*params.CurrentPassword, user.HashedPassword)
+		if err != nil || !match {
+			respondWithError(w, http.StatusForbidden, "Incorrect current password")
+			return
+		}
+	}
+
+	userData := database.UpdateUserParams{
+		ID:          userID,
+		Email:       nullString(params.Email),
+		Handle:      nullString(params.Handle),
+		DisplayName: nullString(params.DisplayName),
+		Bio:         nullString(params.Bio),
+	}
+
+	if params.Password != nil {
+		hash, err := auth.HashPassword(*params.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		userData.HashedPassword = sql.NullString{String: hash, Valid: true}
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := qtx.UpdateUser(req.Context(), userData)
+	if err != nil {
+		if msg, ok := userConflict(err); ok {
+			respondWithError(w, http.StatusConflict, msg)
+			return
+		}
+
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		userRes
+		Token        string `json:"token,omitempty"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+	}
+
+	data := resData{userRes: newUserRes(user)}
+
+	// a password change logs out every other session; the caller gets a
+	// fresh session so they stay signed in
+	if params.Password != nil {
+		if err := qtx.RevokeUserRefreshTokens(req.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		data.RefreshToken, err = issueRefreshToken(req, qtx, userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		data.Token, err = auth.MakeJWT(userID, auth.AllScopes, cfg.jwtKeys, 1*time.Hour)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if params.Email != nil && !user.EmailVerifiedAt.Valid {
+		if err := cfg.sendVerificationEmail(req.Context(), user); err != nil {
+			log.Printf("failed to send verification email to user %s: %v", user.ID, err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+func (cfg *apiConfig) deleteCurrentUser(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	type reqParams struct {
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusForbidden, "Incorrect password")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	deletedAt := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	if err := qtx.SoftDeleteUser(req.Context(), database.SoftDeleteUserParams{ID: user.ID, DeletedAt: deletedAt}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(req.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		PurgeAfter time.Time `json:"purge_after"`
+	}
+
+	data := resData{PurgeAfter: time.Now().UTC().Add(accountDeletionGracePeriod)}
+	respondWithJSON(w, http.StatusAccepted, &data)
+}
+
+func (cfg *apiConfig) exportUserData(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	chirps, err := cfg.db.GetChirpsForExport(req.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	sessions, err := cfg.db.GetSessionsByUserID(req.Context(), database.GetSessionsByUserIDParams{UserID: user.ID, Now: time.Now().UTC()})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type exportedChirp struct {
+		ID            uuid.UUID     `json:"id"`
+		Body          string        `json:"body"`
+		CreatedAt     time.Time     `json:"created_at"`
+		UpdatedAt     time.Time     `json:"updated_at"`
+		ReplyToID     uuid.NullUUID `json:"reply_to_id"`
+		RechirpOfID   uuid.NullUUID `json:"rechirp_of_id"`
+		QuotedChirpID uuid.NullUUID `json:"quoted_chirp_id"`
+	}
+
+	exportedChirps := make([]exportedChirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		exportedChirps = append(exportedChirps, exportedChirp{
+			ID:            chirp.ID,
+			Body:          chirp.Body,
+			CreatedAt:     chirp.CreatedAt,
+			UpdatedAt:     chirp.UpdatedAt,
+			ReplyToID:     chirp.ReplyToID,
+			RechirpOfID:   chirp.RechirpOfID,
+			QuotedChirpID: chirp.QuotedChirpID,
+		})
+	}
+
+	type exportedSession struct {
+		ID         uuid.UUID `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IPAddress  string    `json:"ip_address"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+	}
+
+	exportedSessions := make([]exportedSession, 0, len(sessions))
+	for _, session := range sessions {
+		exportedSessions = append(exportedSessions, exportedSession{
+			ID:         session.FamilyID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			CreatedAt:  session.StartedAt,
+			LastUsedAt: session.LastUsedAt,
+		})
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", newUserRes(user)},
+		{"chirps.json", exportedChirps},
+		{"sessions.json", exportedSessions},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	// the status is already sent, so failures below can only cut the archive short
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			log.Printf("failed to export data for user %s: %v", user.ID, err)
+			return
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			log.Printf("failed to export data for user %s: %v", user.ID, err)
+			return
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		log.Printf("failed to export data for user %s: %v", user.ID, err)
+	}
+}
+
+func (cfg *apiConfig) getCurrentUser(w http.ResponseWriter, req *http.Request) {
+	user := requestUser(req)
+
+	respondWithJSON(w, http.StatusOK, newUserRes(user))
+}
+
+func (cfg *apiConfig) getUserProfile(w http.ResponseWriter, req *http.Request) {
+	profile, err := cfg.db.GetUserProfileByHandle(req.Context(), req.PathValue("handle"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	type resData struct {
+		ID             uuid.UUID `json:"id"`
+		Handle         string    `json:"handle"`
+		DisplayName    string    `json:"display_name"`
+		Bio            string    `json:"bio"`
+		CreatedAt      time.Time `json:"created_at"`
+		IsChirpyRed    bool      `json:"is_chirpy_red"`
+		ChirpCount     int64     `json:"chirp_count"`
+		FollowerCount  int64     `json:"follower_count"`
+		FollowingCount int64     `json:"following_count"`
+	}
+
+	data := resData{
+		ID:             profile.ID,
+		Handle:         profile.Handle.String,
+		DisplayName:    profile.DisplayName,
+		Bio:            profile.Bio,
+		CreatedAt:      profile.CreatedAt,
+		IsChirpyRed:    profile.IsChirpyRed,
+		ChirpCount:     profile.ChirpCount,
+		FollowerCount:  profile.FollowerCount,
+		FollowingCount: profile.FollowingCount,
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+func (cfg *apiConfig) createRefreshToken(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	stored, err := cfg.db.GetRefreshToken(req.Context(), token)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	// tokens issued to OAuth clients are only refreshed at the token endpoint,
+	// which authenticates the client and keeps the scopes the user granted
+	if stored.ClientID.Valid {
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	if err := auth.CheckRefreshToken(stored.ExpiresAt, stored.RevokedAt.Valid); err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			cfg.revokeTokenFamily(w, req, stored.FamilyID)
+			return
+		}
+
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	refreshToken, err := cfg.rotateRefreshToken(req, stored)
+	if errors.Is(err, errRefreshTokenRace) {
+		cfg.revokeTokenFamily(w, req, stored.FamilyID)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	str, err := auth.MakeJWT(stored.UserID, auth.AllScopes, cfg.jwtKeys, 1*time.Hour)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	data := resData{
+		Token:        str,
+		RefreshToken: refreshToken,
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+var errRefreshTokenRace = errors.New("refresh token was rotated concurrently")
+
+// rotateRefreshToken consumes stored and issues its successor in the same
+// family, keeping the client and scopes it was issued to. It returns
+// errRefreshTokenRace when another request rotated stored first.
+func (cfg *apiConfig) rotateRefreshToken(req *http.Request, stored database.RefreshToken) (string, error) {
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	consumed, err := qtx.ConsumeRefreshToken(req.Context(), stored.Token)
+	if err != nil {
+		return "", err
+	}
+
+	if consumed == 0 {
+		return "", errRefreshTokenRace
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshTokenData := database.CreateRefreshTokenParams{
+		Token:       refreshToken,
+		UserID:      stored.UserID,
+		ExpiresAt:   time.Now().UTC().Add(refreshTokenDuration),
+		FamilyID:    stored.FamilyID,
+		ParentToken: sql.NullString{String: stored.Token, Valid: true},
+		UserAgent:   req.UserAgent(),
+		IpAddress:   clientIP(req),
+		ClientID:    stored.ClientID,
+		Scopes:      stored.Scopes,
+	}
+
+	if err := qtx.CreateRefreshToken(req.Context(), refreshTokenData); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// revokeTokenFamily is called when a rotated refresh token is presented again.
+// Every token descended from the same login is revoked, logging out both the
+// legitimate client and whoever replayed the token.
+func (cfg *apiConfig) revokeTokenFamily(w http.ResponseWriter, req *http.Request, familyID uuid.UUID) {
+	if err := cfg.db.RevokeRefreshTokenFamily(req.Context(), familyID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+}
+
+func (cfg *apiConfig) revokeRefreshToken(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	revokeTokenData := database.RevokeRefreshTokenParams{
+		Token:     token,
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: time.Now(),
+	}
+
+	if err := cfg.db.RevokeRefreshToken(req.Context(), revokeTokenData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) getSessions(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	rows, err := cfg.db.GetSessionsByUserID(req.Context(), database.GetSessionsByUserIDParams{UserID: userID, Now: time.Now().UTC()})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type session struct {
+		ID         uuid.UUID `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IPAddress  string    `json:"ip_address"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+	}
+
+	sessions := make([]session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, session{
+			ID:         row.FamilyID,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+			CreatedAt:  row.StartedAt,
+			LastUsedAt: row.LastUsedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, &sessions)
+}
+
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	sessionID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	sessionData := database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	}
+
+	revoked, err := cfg.db.RevokeSession(req.Context(), sessionData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) revokeAllSessions(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	if err := cfg.db.RevokeUserRefreshTokens(req.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) createPersonalAccessToken(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	type reqParams struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays *int     `json:"expires_in_days"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxTokenNameLength {
+		fields["name"] = fmt.Sprintf("must be 1-%d characters", maxTokenNameLength)
+	}
+
+	scopes, err := auth.ParseDelegableScopes(params.Scopes)
+	if err != nil {
+		fields["scopes"] = err.Error()
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInDays != nil {
+		if *params.ExpiresInDays < 1 || *params.ExpiresInDays > maxTokenLifetimeDays {
+			fields["expires_in_days"] = fmt.Sprintf("must be between 1 and %d", maxTokenLifetimeDays)
+		}
+
+		expiresAt = sql.NullTime{
+			Time:  time.Now().UTC().Add(time.Duration(*params.ExpiresInDays) * 24 * time.Hour),
+			Valid: true,
+		}
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	pat, err := cfg.db.CreatePersonalAccessToken(req.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// the token is only stored hashed, so this is the one time it is shown
+	type resData struct {
+		personalAccessTokenRes
+		Token string `json:"token"`
+	}
+
+	data := resData{
+		personalAccessTokenRes: newPersonalAccessTokenRes(pat),
+		Token:                  token,
+	}
+
+	respondWithJSON(w, http.StatusCreated, &data)
+}
+
+func (cfg *apiConfig) getPersonalAccessTokens(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	pats, err := cfg.db.GetPersonalAccessTokens(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := make([]personalAccessTokenRes, 0, len(pats))
+	for _, pat := range pats {
+		res = append(res, newPersonalAccessTokenRes(pat))
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) revokePersonalAccessToken(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	tokenID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	revoked, err := cfg.db.RevokePersonalAccessToken(req.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) createOAuthClient(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	type reqParams struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxTokenNameLength {
+		fields["name"] = fmt.Sprintf("must be 1-%d characters", maxTokenNameLength)
+	}
+
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxRedirectURIs {
+		fields["redirect_uris"] = fmt.Sprintf("must have 1-%d entries", maxRedirectURIs)
+	}
+
+	for _, uri := range params.RedirectURIs {
+		if err := oauth.ValidateRedirectURI(uri); err != nil {
+			fields["redirect_uris"] = err.Error()
+		}
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		var err error
+		secret, err = auth.MakeToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.db.CreateOAuthClient(req.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		RedirectUris: params.RedirectURIs,
+		SecretHash:   secretHash,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// like personal access tokens, the secret is only shown once
+	type resData struct {
+		oauthClientRes
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	data := resData{
+		oauthClientRes: newOAuthClientRes(client),
+		ClientSecret:   secret,
+	}
+
+	respondWithJSON(w, http.StatusCreated, &data)
+}
+
+func (cfg *apiConfig) getOAuthClients(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	clients, err := cfg.db.GetOAuthClientsByOwner(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := make([]oauthClientRes, 0, len(clients))
+	for _, client := range clients {
+		res = append(res, newOAuthClientRes(client))
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) deleteOAuthClient(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	clientID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	// codes, consents and refresh tokens issued to the client cascade
+	deleted, err := cfg.db.DeleteOAuthClient(req.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest holds the parameters of an RFC 6749 authorization
+// request, read from the query string by getAuthorization and from the body
+// by approveAuthorization.
+type authorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// checkAuthorizationRequest validates an authorization request, returning the
+// client and the scopes it asks for, or an OAuth error code and description.
+func (cfg *apiConfig) checkAuthorizationRequest(ctx context.Context, ar authorizationRequest) (database.OauthClient, []string, string, string) {
+	clientID, err := uuid.Parse(ar.ClientID)
+	if err != nil {
+		return database.OauthClient{}, nil, oauth.ErrInvalidRequest, "unknown client_id"
+	}
+
+	client, err := cfg.db.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		return database.OauthClient{}, nil, oauth.ErrInvalidRequest, "unknown client_id"
+	}
+
+	if !oauth.MatchRedirectURI(client.RedirectUris, ar.RedirectURI) {
+		return database.OauthClient{}, nil, oauth.ErrInvalidRequest, "redirect_uri is not registered for this client"
+	}
+
+	if ar.ResponseType != "code" {
+		return database.OauthClient{}, nil, oauth.ErrUnsupportedResponse, "response_type must be code"
+	}
+
+	if ar.CodeChallengeMethod != oauth.ChallengeMethodS256 || !oauth.ValidCodeChallenge(ar.CodeChallenge) {
+		return database.OauthClient{}, nil, oauth.ErrInvalidRequest, "an S256 code_challenge is required"
+	}
+
+	scopes, err := auth.ParseDelegableScopes(strings.Fields(ar.Scope))
+	if err != nil {
+		return database.OauthClient{}, nil, oauth.ErrInvalidScope, err.Error()
+	}
+
+	return client, scopes, "", ""
+}
+
+// getAuthorization describes a pending authorization request so Chirpy's
+// frontend can render the consent screen for the logged in user.
+func (cfg *apiConfig) getAuthorization(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	query := req.URL.Query()
+	ar := authorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	client, scopes, errCode, errDescription := cfg.checkAuthorizationRequest(req.Context(), ar)
+	if errCode != "" {
+		respondWithOAuthError(w, http.StatusBadRequest, errCode, errDescription)
+		return
+	}
+
+	// consent already covering every requested scope lets the frontend skip
+	// the prompt and approve straight away
+	consented := false
+	consent, err := cfg.db.GetOAuthConsent(req.Context(), database.GetOAuthConsentParams{
+		UserID:   userID,
+		ClientID: client.ID,
+	})
+	if err == nil {
+		consented = true
+		for _, scope := range scopes {
+			if !slices.Contains(consent.Scopes, scope) {
+				consented = false
+			}
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		ClientID    uuid.UUID `json:"client_id"`
+		ClientName  string    `json:"client_name"`
+		RedirectURI string    `json:"redirect_uri"`
+		Scopes      []string  `json:"scopes"`
+		Consented   bool      `json:"consented"`
+	}
+
+	data := resData{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: ar.RedirectURI,
+		Scopes:      scopes,
+		Consented:   consented,
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+// approveAuthorization records the user's decision and returns where to send
+// the browser: back to the client with either a code or access_denied.
+func (cfg *apiConfig) approveAuthorization(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	type reqParams struct {
+		authorizationRequest
+		Approve bool `json:"approve"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	client, scopes, errCode, errDescription := cfg.checkAuthorizationRequest(req.Context(), params.authorizationRequest)
+	if errCode != "" {
+		respondWithOAuthError(w, http.StatusBadRequest, errCode, errDescription)
+		return
+	}
+
+	type resData struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	redirect := url.Values{}
+	if params.State != "" {
+		redirect.Set("state", params.State)
+	}
+
+	if !params.Approve {
+		redirect.Set("error", oauth.ErrAccessDenied)
+		respondWithJSON(w, http.StatusOK, &resData{RedirectTo: oauth.RedirectWith(params.RedirectURI, redirect)})
+		return
+	}
+
+	code, err := auth.MakeToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.UpsertOAuthConsent(req.Context(), database.UpsertOAuthConsentParams{
+		UserID:   userID,
+		ClientID: client.ID,
+		Scopes:   scopes,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = qtx.CreateOAuthAuthorizationCode(req.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectUri:   params.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: params.CodeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(authorizationCodeDuration),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	redirect.Set("code", code)
+	respondWithJSON(w, http.StatusOK, &resData{RedirectTo: oauth.RedirectWith(params.RedirectURI, redirect)})
+}
+
+// oauthToken is the RFC 6749 token endpoint. It takes form encoded requests
+// and answers with the standard token response or error body.
+func (cfg *apiConfig) oauthToken(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := req.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidRequest, "malformed form body")
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(req)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		respondWithOAuthError(w, http.StatusUnauthorized, oauth.ErrInvalidClient, "client authentication failed")
+		return
+	}
+
+	var userID uuid.UUID
+	var scopes []string
+	var refreshToken string
+
+	switch req.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, err := cfg.db.ConsumeOAuthAuthorizationCode(req.Context(), database.ConsumeOAuthAuthorizationCodeParams{
+			Now:      time.Now().UTC(),
+			CodeHash: auth.HashToken(req.PostForm.Get("code")),
+		})
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "invalid or expired code")
+			return
+		}
+
+		if code.ClientID != client.ID || code.RedirectUri != req.PostForm.Get("redirect_uri") {
+			respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "code was issued to another client or redirect_uri")
+			return
+		}
+
+		if !oauth.VerifyPKCE(req.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "code_verifier does not match the code_challenge")
+			return
+		}
+
+		userID, scopes = code.UserID, code.Scopes
+		refreshToken, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		refreshTokenData := database.CreateRefreshTokenParams{
+			Token:     refreshToken,
+			UserID:    userID,
+			ExpiresAt: time.Now().UTC().Add(refreshTokenDuration),
+			FamilyID:  uuid.New(),
+			UserAgent: req.UserAgent(),
+			IpAddress: clientIP(req),
+			ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+			Scopes:    scopes,
+		}
+
+		if err := cfg.db.CreateRefreshToken(req.Context(), refreshTokenData); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	case "refresh_token":
+		stored, err := cfg.db.GetRefreshToken(req.Context(), req.PostForm.Get("refresh_token"))
+		if err != nil || stored.ClientID.UUID != client.ID || !stored.ClientID.Valid {
+			respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "invalid refresh_token")
+			return
+		}
+
+		if err := auth.CheckRefreshToken(stored.ExpiresAt, stored.RevokedAt.Valid); err != nil {
+			if errors.Is(err, auth.ErrRefreshTokenReused) {
+				cfg.revokeOAuthTokenFamily(w, req, stored.FamilyID)
+				return
+			}
+
+			respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "invalid refresh_token")
+			return
+		}
+
+		refreshToken, err = cfg.rotateRefreshToken(req, stored)
+		if errors.Is(err, errRefreshTokenRace) {
+			cfg.revokeOAuthTokenFamily(w, req, stored.FamilyID)
+			return
+		}
+
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		userID, scopes = stored.UserID, stored.Scopes
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrUnsupportedGrantType, "grant_type must be authorization_code or refresh_token")
+		return
+	}
+
+	accessToken, err := auth.MakeJWT(userID, scopes, cfg.jwtKeys, 1*time.Hour)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+
+	data := resData{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Hour.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+// revokeOAuthTokenFamily is revokeTokenFamily for the token endpoint, which
+// must answer in the OAuth error format.
+func (cfg *apiConfig) revokeOAuthTokenFamily(w http.ResponseWriter, req *http.Request, familyID uuid.UUID) {
+	if err := cfg.db.RevokeRefreshTokenFamily(req.Context(), familyID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithOAuthError(w, http.StatusBadRequest, oauth.ErrInvalidGrant, "invalid refresh_token")
+}
+
+// authenticateOAuthClient identifies the client calling the token endpoint.
+// Confidential clients send their secret with HTTP Basic or in the form;
+// public clients only send client_id and are held to PKCE.
+func (cfg *apiConfig) authenticateOAuthClient(req *http.Request) (database.OauthClient, bool) {
+	clientIDParam, secret, hasBasic := req.BasicAuth()
+	if !hasBasic {
+		clientIDParam = req.PostForm.Get("client_id")
+		secret = req.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDParam)
+	if err != nil {
+		return database.OauthClient{}, false
+	}
+
+	client, err := cfg.db.GetOAuthClient(req.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, false
+	}
+
+	if !client.SecretHash.Valid {
+		return client, secret == ""
+	}
+
+	match := subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) == 1
+	return client, match
+}
+
+func (cfg *apiConfig) getOAuthConsents(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	consents, err := cfg.db.GetOAuthConsentsByUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type consent struct {
+		ClientID   uuid.UUID `json:"client_id"`
+		ClientName string    `json:"client_name"`
+		Scopes     []string  `json:"scopes"`
+		CreatedAt  time.Time `json:"created_at"`
+		UpdatedAt  time.Time `json:"updated_at"`
+	}
+
+	res := make([]consent, 0, len(consents))
+	for _, c := range consents {
+		res = append(res, consent{
+			ClientID:   c.ClientID,
+			ClientName: c.ClientName,
+			Scopes:     c.Scopes,
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+// revokeOAuthConsent disconnects a client from the user's account, revoking
+// every refresh token it holds. Its access tokens expire within the hour.
+func (cfg *apiConfig) revokeOAuthConsent(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	clientID, err := uuid.Parse(req.PathValue("client_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	deleted, err := qtx.DeleteOAuthConsent(req.Context(), database.DeleteOAuthConsentParams{
+		UserID:   userID,
+		ClientID: clientID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	err = qtx.RevokeClientRefreshTokens(req.Context(), database.RevokeClientRefreshTokensParams{
+		UserID:   userID,
+		ClientID: uuid.NullUUID{UUID: clientID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) createChirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	if !requireVerifiedEmail(w, requestUser(req)) {
+		return
+	}
+
+	type reqParams struct {
+		Body          string     `json:"body"`
+		ReplyToID     *uuid.UUID `json:"reply_to_id"`
+		QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if limit := 140; len(params.Body) > limit {
+		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	}
+
+	verdict := cfg.moderationFilter.Load().Moderate(params.Body)
+	if verdict.Rejected {
+		respondWithValidationErrors(w, map[string]string{"body": "contains a word that is not allowed"})
+		return
+	}
+
+	replyToID, err := cfg.resolveChirpRef(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, params.ReplyToID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
+		return
+	}
+
+	quotedChirpID, err := cfg.resolveChirpRef(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, params.QuotedChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Quoted chirp does not exist")
+		return
+	}
+
+	chirpData := database.CreateChirpParams{
+		UserID:        userID,
+		Body:          verdict.Text,
+		ReplyToID:     replyToID,
+		QuotedChirpID: quotedChirpID,
+	}
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.CreateChirp(req.Context(), chirpData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	tagData := database.CreateChirpTagsParams{ChirpID: chirp.ID, Tags: parse.Hashtags(chirp.Body)}
+	if err := qtx.CreateChirpTags(req.Context(), tagData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// mentions of unknown handles are left as plain text
+	mentionData := database.CreateChirpMentionsParams{ChirpID: chirp.ID, Handles: parse.Mentions(chirp.Body)}
+	if err := qtx.CreateChirpMentions(req.Context(), mentionData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if len(verdict.Flagged) > 0 {
+		flagData := database.CreateChirpFlagParams{ChirpID: chirp.ID, Words: verdict.Flagged}
+		if err := qtx.CreateChirpFlag(req.Context(), flagData); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res, err := cfg.chirpResponse(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, &res)
+}
+
+// resolveChirpRef checks that a referenced chirp exists. References to a
+// rechirp are redirected to the original chirp it reposts.
+func (cfg *apiConfig) resolveChirpRef(ctx context.Context, viewerID uuid.NullUUID, id *uuid.UUID) (uuid.NullUUID, error) {
+	if id == nil {
+		return uuid.NullUUID{}, nil
+	}
+
+	chirp, err := cfg.db.GetChirpByID(ctx, database.GetChirpByIDParams{ID: *id, ViewerID: viewerID})
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	if chirp.RechirpOfID.Valid {
+		return chirp.RechirpOfID, nil
+	}
+
+	return uuid.NullUUID{UUID: chirp.ID, Valid: true}, nil
+}
+
+func (cfg *apiConfig) getChirps(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	authorID := uuid.NullUUID{}
+	if s := query.Get("author_id"); len(s) > 0 {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			return
+		}
+
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	sortOrder := query.Get("sort")
+	if len(sortOrder) == 0 {
+		sortOrder = "asc"
+	}
+
+	if sortOrder != "asc" && sortOrder != "desc" {
+		respondWithError(w, http.StatusBadRequest, "Invalid sort")
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var chirps []database.Chirp
+	if sortOrder == "desc" {
+		chirps, err = cfg.db.GetChirpsDesc(req.Context(), database.GetChirpsDescParams{
+			AuthorID:       authorID,
+			ViewerID:       requestViewer(req),
+			AfterCreatedAt: page.afterCreatedAt(),
+			AfterID:        page.afterID(),
+			Limit:          page.fetchLimit(),
+		})
+	} else {
+		chirps, err = cfg.db.GetChirps(req.Context(), database.GetChirpsParams{
+			AuthorID:       authorID,
+			ViewerID:       requestViewer(req),
+			AfterCreatedAt: page.afterCreatedAt(),
+			AfterID:        page.afterID(),
+			Limit:          page.fetchLimit(),
+		})
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirps, next := paginate(chirps, page.limit, chirpCursor)
+	res, err := cfg.chirpResponses(req.Context(), requestViewer(req), chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[chirpRes]{Items: res, NextCursor: next})
+}
+
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if len(q) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Missing search query")
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if s := query.Get("author_id"); len(s) > 0 {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			return
+		}
+
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	limit, err := pagination.ParseLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	searchData := database.SearchChirpsParams{
+		Query:    q,
+		AuthorID: authorID,
+		ViewerID: requestViewer(req),
+		Limit:    int32(limit + 1),
+	}
+
+	if s := query.Get("cursor"); len(s) > 0 {
+		cursor, err := pagination.DecodeRankCursor(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		searchData.AfterRank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		searchData.AfterCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		searchData.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	rows, err := cfg.db.SearchChirps(req.Context(), searchData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = pagination.RankCursor{Rank: last.Rank, Cursor: chirpCursor(last.Chirp)}.Encode()
+	}
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+
+	res, err := cfg.chirpResponses(req.Context(), requestViewer(req), chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[chirpRes]{Items: res, NextCursor: next})
+}
+
+func (cfg *apiConfig) getChirpsByTag(w http.ResponseWriter, req *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(req.PathValue("tag"), "#"))
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := cfg.db.GetChirpsByTag(req.Context(), database.GetChirpsByTagParams{
+		Tag:            tag,
+		ViewerID:       requestViewer(req),
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirps, next := paginate(chirps, page.limit, chirpCursor)
+	res, err := cfg.chirpResponses(req.Context(), requestViewer(req), chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[chirpRes]{Items: res, NextCursor: next})
+}
+
+func (cfg *apiConfig) getTrendingTags(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	window := 24 * time.Hour
+	if s := query.Get("window"); len(s) > 0 {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > 7*24*time.Hour {
+			respondWithError(w, http.StatusBadRequest, "Invalid window")
+			return
+		}
+
+		window = d
+	}
+
+	limit, err := pagination.ParseLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	trendingData := database.GetTrendingTagsParams{
+		Since: time.Now().UTC().Add(-window),
+		Limit: int32(limit),
+	}
+
+	rows, err := cfg.db.GetTrendingTags(req.Context(), trendingData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type tagRes struct {
+		Tag  string `json:"tag"`
+		Uses int64  `json:"uses"`
+	}
+
+	tags := make([]tagRes, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, tagRes{Tag: row.Tag, Uses: row.Uses})
+	}
+
+	respondWithJSON(w, http.StatusOK, &tags)
+}
+
+func chirpCursor(c database.Chirp) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func (cfg *apiConfig) getChirpByID(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: requestViewer(req)})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	res, err := cfg.chirpResponse(req.Context(), requestViewer(req), chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) getReplies(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: requestViewer(req)}); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	replies, err := cfg.replyPage(req, chirpID, page)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &replies)
+}
+
+func (cfg *apiConfig) getThread(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	viewerID := requestViewer(req)
+	chirp, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: viewerID})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	ancestors, err := cfg.db.GetChirpAncestors(req.Context(), database.GetChirpAncestorsParams{ChirpID: chirpID, ViewerID: viewerID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirps, err := cfg.chirpResponses(req.Context(), viewerID, append(ancestors, chirp))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	replies, err := cfg.replyPage(req, chirpID, page)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	type resData struct {
+		Ancestors []chirpRes        `json:"ancestors"`
+		Chirp     chirpRes          `json:"chirp"`
+		Replies   pageRes[chirpRes] `json:"replies"`
+	}
+
+	data := resData{
+		Ancestors: chirps[:len(chirps)-1],
+		Chirp:     chirps[len(chirps)-1],
+		Replies:   replies,
+	}
+
+	respondWithJSON(w, http.StatusOK, &data)
+}
+
+func (cfg *apiConfig) replyPage(req *http.Request, chirpID uuid.UUID, page pageParams) (pageRes[chirpRes], error) {
+	replies, err := cfg.db.GetReplies(req.Context(), database.GetRepliesParams{
+		ChirpID:        chirpID,
+		ViewerID:       requestViewer(req),
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		return pageRes[chirpRes]{}, err
+	}
+
+	replies, next := paginate(replies, page.limit, chirpCursor)
+	res, err := cfg.chirpResponses(req.Context(), requestViewer(req), replies)
+	if err != nil {
+		return pageRes[chirpRes]{}, err
+	}
+
+	return pageRes[chirpRes]{Items: res, NextCursor: next}, nil
+}
+
+func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: uuid.NullUUID{UUID: userID, Valid: true}})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+
+	// replies are kept and detached from the deleted chirp by the
+	// ON DELETE SET NULL constraint on chirps.reply_to_id
+	if err := cfg.db.DeleteChirp(req.Context(), chirpID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	if !requireVerifiedEmail(w, requestUser(req)) {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	originalID, err := cfg.resolveChirpRef(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	rechirpData := database.CreateRechirpParams{
+		UserID:      userID,
+		RechirpOfID: originalID,
+	}
+
+	status := http.StatusCreated
+	chirp, err := cfg.db.CreateRechirp(req.Context(), rechirpData)
+	if errors.Is(err, sql.ErrNoRows) {
+		// already rechirped, return the existing one
+		status = http.StatusOK
+		chirp, err = cfg.db.GetRechirp(req.Context(), database.GetRechirpParams(rechirpData))
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res, err := cfg.chirpResponse(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, status, &res)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	rechirpData := database.DeleteRechirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+
+	deleted, err := cfg.db.DeleteRechirp(req.Context(), rechirpData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if _, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: uuid.NullUUID{UUID: userID, Valid: true}}); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	likeData := database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+
+	if err := cfg.db.LikeChirp(req.Context(), likeData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	unlikeData := database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+
+	if err := cfg.db.UnlikeChirp(req.Context(), unlikeData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	followeeID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You cannot follow yourself")
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(req.Context(), followeeID); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	followData := database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+
+	followed, err := cfg.db.FollowUser(req.Context(), followData)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// nothing is inserted when already following, or when blocked
+	if followed == 0 {
+		blocked, err := cfg.db.IsBlockedBetween(req.Context(), database.IsBlockedBetweenParams{UserID: userID, OtherID: followeeID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "You cannot follow this user")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	followeeID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	followData := database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+
+	if err := cfg.db.UnfollowUser(req.Context(), followData); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type followRes struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func followCursor(f followRes) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: f.FollowedAt, ID: f.UserID}
+}
+
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(req.Context(), userID); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	rows, err := cfg.db.GetFollowers(req.Context(), database.GetFollowersParams{
+		UserID:         userID,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	followers := make([]followRes, 0, len(rows))
+	for _, row := range rows {
+		followers = append(followers, followRes{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+
+	followers, next := paginate(followers, page.limit, followCursor)
+	respondWithJSON(w, http.StatusOK, &pageRes[followRes]{Items: followers, NextCursor: next})
+}
+
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(req.Context(), userID); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	rows, err := cfg.db.GetFollowing(req.Context(), database.GetFollowingParams{
+		UserID:         userID,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	following := make([]followRes, 0, len(rows))
+	for _, row := range rows {
+		following = append(following, followRes{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+
+	following, next := paginate(following, page.limit, followCursor)
+	respondWithJSON(w, http.StatusOK, &pageRes[followRes]{Items: following, NextCursor: next})
+}
+
+// blockUser hides the two users' chirps from each other and stops the
+// blocked user from following, replying to, liking or mentioning the
+// blocker. Existing follows between them are removed.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	blockedID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if blockedID == userID {
+		respondWithError(w, http.StatusBadRequest, "You cannot block yourself")
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(req.Context(), blockedID); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	if err := qtx.BlockUser(req.Context(), database.BlockUserParams{BlockerID: userID, BlockedID: blockedID}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	for _, follow := range []database.UnfollowUserParams{
+		{FollowerID: userID, FolloweeID: blockedID},
+		{FollowerID: blockedID, FolloweeID: userID},
+	} {
+		if err := qtx.UnfollowUser(req.Context(), follow); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	blockedID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if err := cfg.db.UnblockUser(req.Context(), database.UnblockUserParams{BlockerID: userID, BlockedID: blockedID}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type blockRes struct {
+	UserID    uuid.UUID `json:"user_id"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+func (cfg *apiConfig) getBlockedUsers(w http.ResponseWriter, req *http.Request) {
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.db.GetBlockedUsers(req.Context(), database.GetBlockedUsersParams{
+		UserID:         requestUser(req).ID,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	blocks := make([]blockRes, 0, len(rows))
+	for _, row := range rows {
+		blocks = append(blocks, blockRes{UserID: row.UserID, BlockedAt: row.CreatedAt})
+	}
+
+	blocks, next := paginate(blocks, page.limit, func(b blockRes) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: b.BlockedAt, ID: b.UserID}
+	})
+	respondWithJSON(w, http.StatusOK, &pageRes[blockRes]{Items: blocks, NextCursor: next})
+}
+
+// muteUser hides a user's chirps from the caller's timeline and from chirp
+// listings that aren't filtered to that user. Unlike a block, the muted user
+// isn't affected.
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	mutedID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if mutedID == userID {
+		respondWithError(w, http.StatusBadRequest, "You cannot mute yourself")
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(req.Context(), mutedID); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err := cfg.db.MuteUser(req.Context(), database.MuteUserParams{MuterID: userID, MutedID: mutedID}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	mutedID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if err := cfg.db.UnmuteUser(req.Context(), database.UnmuteUserParams{MuterID: userID, MutedID: mutedID}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type muteRes struct {
+	UserID  uuid.UUID `json:"user_id"`
+	MutedAt time.Time `json:"muted_at"`
+}
+
+func (cfg *apiConfig) getMutedUsers(w http.ResponseWriter, req *http.Request) {
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.db.GetMutedUsers(req.Context(), database.GetMutedUsersParams{
+		UserID:         requestUser(req).ID,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	mutes := make([]muteRes, 0, len(rows))
+	for _, row := range rows {
+		mutes = append(mutes, muteRes{UserID: row.UserID, MutedAt: row.CreatedAt})
+	}
+
+	mutes, next := paginate(mutes, page.limit, func(m muteRes) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.MutedAt, ID: m.UserID}
+	})
+	respondWithJSON(w, http.StatusOK, &pageRes[muteRes]{Items: mutes, NextCursor: next})
+}
+
+func (cfg *apiConfig) getTimeline(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := cfg.db.GetTimeline(req.Context(), database.GetTimelineParams{
+		UserID:         userID,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirps, next := paginate(chirps, page.limit, chirpCursor)
+	res, err := cfg.chirpResponses(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[chirpRes]{Items: res, NextCursor: next})
+}
+
+func (cfg *apiConfig) upgradeChirpyMembership(w http.ResponseWriter, req *http.Request) {
+	if key, err := auth.GetAPIKey(req.Header); err != nil || key != cfg.polkaKey {
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	type reqParams struct {
+		Event string `json:"event"`
+		Data  struct {
+			UserID string `json:"user_id"`
+		} `json:"data"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if params.Event != "user.upgraded" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	userID, err := uuid.Parse(params.Data.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirpyData := database.SetUserChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: true,
+	}
+
+	if err := cfg.db.SetUserChirpyRed(req.Context(), chirpyData); err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, req *http.Request) {
+	userID := requestUser(req).ID
+
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	if !slices.Contains(reportReasons, params.Reason) {
+		fields["reason"] = "must be one of " + strings.Join(reportReasons, ", ")
+	}
+
+	params.Details = strings.TrimSpace(params.Details)
+	if len(params.Details) > maxReportDetailsLength {
+		fields["details"] = fmt.Sprintf("must be at most %d characters", maxReportDetailsLength)
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), database.GetChirpByIDParams{ID: chirpID, ViewerID: uuid.NullUUID{UUID: userID, Valid: true}})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You cannot report your own chirp")
+		return
+	}
+
+	report, err := cfg.db.CreateReport(req.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if isUniqueViolation(err, "reports_chirp_id_reporter_id_key") {
+		respondWithError(w, http.StatusConflict, "You have already reported this chirp")
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := newReportRes(report)
+	respondWithJSON(w, http.StatusCreated, &res)
+}
+
+func (cfg *apiConfig) getReports(w http.ResponseWriter, req *http.Request) {
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+
+	if !slices.Contains([]string{"open", "resolved", "dismissed"}, status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.db.GetReports(req.Context(), database.GetReportsParams{
+		Status:         status,
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	rows, next := paginate(rows, page.limit, func(r database.GetReportsRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: r.Report.CreatedAt, ID: r.Report.ID}
+	})
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+
+	decorated, err := cfg.chirpResponses(req.Context(), uuid.NullUUID{}, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := make([]queuedReportRes, 0, len(rows))
+	for i, row := range rows {
+		res = append(res, queuedReportRes{reportRes: newReportRes(row.Report), Chirp: decorated[i]})
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[queuedReportRes]{Items: res, NextCursor: next})
+}
+
+// closeReport resolves or dismisses an open report without touching the
+// chirp. Hiding a chirp resolves its reports by itself.
+func (cfg *apiConfig) closeReport(w http.ResponseWriter, req *http.Request) {
+	reportID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Status string `json:"status"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if params.Status != "resolved" && params.Status != "dismissed" {
+		respondWithValidationErrors(w, map[string]string{"status": "must be resolved or dismissed"})
+		return
+	}
+
+	report, err := cfg.db.CloseReport(req.Context(), database.CloseReportParams{Status: params.Status, ID: reportID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "No open report with that id")
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := newReportRes(report)
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) hideChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.setChirpHidden(w, req, true)
+}
+
+func (cfg *apiConfig) restoreChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.setChirpHidden(w, req, false)
+}
+
+// setChirpHidden hides a chirp from everyone but its author, resolving the
+// reports against it, or restores it.
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, req *http.Request, hidden bool) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	_, err = qtx.SetChirpHidden(req.Context(), database.SetChirpHiddenParams{Hidden: hidden, ID: chirpID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if hidden {
+		if err := qtx.ResolveChirpReports(req.Context(), chirpID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// suspendUser locks a user out until a given time, or until lifted. Their
+// sessions are revoked and their tokens stop working, but their chirps stay
+// up; hide those separately if needed.
+func (cfg *apiConfig) suspendUser(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Until  *time.Time `json:"until"`
+		Reason string     `json:"reason"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	until := sql.NullTime{}
+	if params.Until != nil {
+		if !params.Until.After(time.Now()) {
+			fields["until"] = "must be in the future"
+		}
+
+		until = sql.NullTime{Time: params.Until.UTC(), Valid: true}
+	}
+
+	params.Reason = strings.TrimSpace(params.Reason)
+	if len(params.Reason) > maxSuspensionReasonLength {
+		fields["reason"] = fmt.Sprintf("must be at most %d characters", maxSuspensionReasonLength)
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	target, err := cfg.db.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// moderators can't suspend each other, and admins have to be demoted first
+	if roleRanks[target.Role] >= roleRanks[requestUser(req).Role] {
+		respondWithError(w, http.StatusForbidden, "You cannot suspend a user with the same or a higher role")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	suspended, err := qtx.SuspendUser(req.Context(), database.SuspendUserParams{ID: userID, SuspendedUntil: until})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if suspended == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(req.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	detail := "indefinitely"
+	if until.Valid {
+		detail = "until " + until.Time.Format(time.RFC3339)
+	}
+
+	if params.Reason != "" {
+		detail += ": " + params.Reason
+	}
+
+	err = qtx.CreateAuditLogEntry(req.Context(), database.CreateAuditLogEntryParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+		Action:    "user.suspended",
+		IpAddress: clientIP(req),
+		Detail:    detail,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unsuspendUser(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	unsuspended, err := cfg.db.UnsuspendUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if unsuspended == 0 {
+		respondWithError(w, http.StatusNotFound, "User is not suspended")
+		return
+	}
+
+	err = cfg.db.CreateAuditLogEntry(req.Context(), database.CreateAuditLogEntryParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+		Action:    "user.unsuspended",
+		IpAddress: clientIP(req),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setUserRole promotes or demotes a user. Admins can't change their own role,
+// so there is always at least one admin left.
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Role string `json:"role"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	if _, ok := roleRanks[params.Role]; !ok {
+		respondWithValidationErrors(w, map[string]string{"role": "must be user, moderator or admin"})
+		return
+	}
+
+	if userID == requestUser(req).ID {
+		respondWithError(w, http.StatusBadRequest, "You cannot change your own role")
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	previous, err := qtx.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	user, err := qtx.SetUserRole(req.Context(), database.SetUserRoleParams{ID: userID, Role: params.Role})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if previous.Role != user.Role {
+		err = qtx.CreateAuditLogEntry(req.Context(), database.CreateAuditLogEntryParams{
+			UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+			Action:    "user.role_changed",
+			IpAddress: clientIP(req),
+			Detail:    fmt.Sprintf("%s to %s by %s", previous.Role, user.Role, requestUser(req).ID),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &userRoleRes{ID: user.ID, Role: user.Role})
+}
+
+// loadModerationFilter rebuilds the chirp filter from the lists in the
+// database and those read from MODERATION_FILE at startup.
+func (cfg *apiConfig) loadModerationFilter(ctx context.Context) error {
+	lists, err := cfg.moderationLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	all := slices.Clone(cfg.moderationFileLists)
+	for _, list := range lists {
+		all = append(all, moderation.List{Name: list.Name, Action: list.Action, Words: list.Words})
+	}
+
+	cfg.moderationFilter.Store(moderation.NewFilter(all))
+	return nil
+}
+
+func (cfg *apiConfig) moderationLists(ctx context.Context) ([]moderationListRes, error) {
+	lists, err := cfg.db.GetModerationLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	words, err := cfg.db.GetModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byList := map[uuid.UUID][]string{}
+	for _, w := range words {
+		byList[w.ListID] = append(byList[w.ListID], w.Word)
+	}
+
+	res := make([]moderationListRes, 0, len(lists))
+	for _, list := range lists {
+		res = append(res, newModerationListRes(list, byList[list.ID]))
+	}
+
+	return res, nil
+}
+
+func (cfg *apiConfig) moderationListResponse(ctx context.Context, list database.ModerationList) (moderationListRes, error) {
+	words, err := cfg.db.GetModerationListWords(ctx, list.ID)
+	if err != nil {
+		return moderationListRes{}, err
+	}
+
+	return newModerationListRes(list, words), nil
+}
+
+// normalizeModerationWords folds words the way the filter matches them and
+// drops duplicates.
+func normalizeModerationWords(words []string) ([]string, error) {
+	normalized := []string{}
+	for _, word := range words {
+		n, err := moderation.NormalizeWord(word)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(normalized, n) {
+			normalized = append(normalized, n)
+		}
+	}
+
+	return normalized, nil
+}
+
+func (cfg *apiConfig) getModerationLists(w http.ResponseWriter, req *http.Request) {
+	lists, err := cfg.moderationLists(req.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &lists)
+}
+
+func (cfg *apiConfig) createModerationList(w http.ResponseWriter, req *http.Request) {
+	type reqParams struct {
+		Name   string            `json:"name"`
+		Action moderation.Action `json:"action"`
+		Words  []string          `json:"words"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxModerationListNameLength {
+		fields["name"] = fmt.Sprintf("must be 1-%d characters", maxModerationListNameLength)
+	}
+
+	if !params.Action.Valid() {
+		fields["action"] = moderation.ErrInvalidAction.Error()
+	}
+
+	words, err := normalizeModerationWords(params.Words)
+	if err != nil {
+		fields["words"] = err.Error()
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	list, err := qtx.CreateModerationList(req.Context(), database.CreateModerationListParams{
+		Name:   params.Name,
+		Action: string(params.Action),
+	})
+	if isUniqueViolation(err, "moderation_lists_name_key") {
+		respondWithError(w, http.StatusConflict, "A list with that name already exists")
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := qtx.AddModerationWords(req.Context(), database.AddModerationWordsParams{ListID: list.ID, Words: words}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := cfg.loadModerationFilter(req.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	slices.Sort(words)
+	res := newModerationListRes(list, words)
+	respondWithJSON(w, http.StatusCreated, &res)
+}
+
+func (cfg *apiConfig) updateModerationList(w http.ResponseWriter, req *http.Request) {
+	listID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Name   *string            `json:"name"`
+		Action *moderation.Action `json:"action"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	fields := map[string]string{}
+	listData := database.UpdateModerationListParams{ID: listID}
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		if name == "" || len(name) > maxModerationListNameLength {
+			fields["name"] = fmt.Sprintf("must be 1-%d characters", maxModerationListNameLength)
+		}
+
+		listData.Name = sql.NullString{String: name, Valid: true}
+	}
+
+	if params.Action != nil {
+		if !params.Action.Valid() {
+			fields["action"] = moderation.ErrInvalidAction.Error()
+		}
+
+		listData.Action = sql.NullString{String: string(*params.Action), Valid: true}
+	}
+
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, fields)
+		return
+	}
+
+	list, err := cfg.db.UpdateModerationList(req.Context(), listData)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if isUniqueViolation(err, "moderation_lists_name_key") {
+		respondWithError(w, http.StatusConflict, "A list with that name already exists")
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := cfg.loadModerationFilter(req.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res, err := cfg.moderationListResponse(req.Context(), list)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) deleteModerationList(w http.ResponseWriter, req *http.Request) {
+	listID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	deleted, err := cfg.db.DeleteModerationList(req.Context(), listID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err := cfg.loadModerationFilter(req.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) addModerationWords(w http.ResponseWriter, req *http.Request) {
+	listID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	type reqParams struct {
+		Words []string `json:"words"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := reqParams{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	words, err := normalizeModerationWords(params.Words)
+	if err != nil || len(words) == 0 {
+		respondWithValidationErrors(w, map[string]string{"words": moderation.ErrInvalidWord.Error()})
+		return
+	}
+
+	list, err := cfg.db.GetModerationList(req.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := cfg.db.AddModerationWords(req.Context(), database.AddModerationWordsParams{ListID: list.ID, Words: words}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := cfg.loadModerationFilter(req.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res, err := cfg.moderationListResponse(req.Context(), list)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &res)
+}
+
+func (cfg *apiConfig) deleteModerationWord(w http.ResponseWriter, req *http.Request) {
+	listID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	word, err := moderation.NormalizeWord(req.PathValue("word"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	deleted, err := cfg.db.DeleteModerationWord(req.Context(), database.DeleteModerationWordParams{
+		ListID: listID,
+		Word:   word,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err := cfg.loadModerationFilter(req.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) getFlaggedChirps(w http.ResponseWriter, req *http.Request) {
+	page, err := parsePageParams(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.db.GetFlaggedChirps(req.Context(), database.GetFlaggedChirpsParams{
+		AfterCreatedAt: page.afterCreatedAt(),
+		AfterID:        page.afterID(),
+		Limit:          page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	rows, next := paginate(rows, page.limit, func(r database.GetFlaggedChirpsRow) pagination.Cursor {
+		return chirpCursor(r.Chirp)
+	})
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+
+	decorated, err := cfg.chirpResponses(req.Context(), uuid.NullUUID{}, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	res := make([]flaggedChirpRes, 0, len(rows))
+	for i, row := range rows {
+		res = append(res, flaggedChirpRes{Chirp: decorated[i], Words: row.Words, FlaggedAt: row.FlaggedAt})
+	}
+
+	respondWithJSON(w, http.StatusOK, &pageRes[flaggedChirpRes]{Items: res, NextCursor: next})
+}
+
+// dismissChirpFlag clears a flag after review, leaving the chirp in place.
+func (cfg *apiConfig) dismissChirpFlag(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	deleted, err := cfg.db.DeleteChirpFlag(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if deleted == 0 {
 		respondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 		return
 	}
@@ -447,6 +4102,11 @@ func (cfg *apiConfig) upgradeChirpyMembership(w http.ResponseWriter, req *http.R
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (cfg *apiConfig) getJWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
+
 func getServerReadiness(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -1,11 +1,14 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -13,6 +16,11 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
 func HashPassword(password string) (string, error) {
 	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
 	if err != nil {
@@ -31,47 +39,139 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	}
+// dummyHash is hashed on first use rather than at startup, since argon2id is
+// deliberately slow.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("chirpy-dummy-password")
+	return hash
+})
+
+// CheckDummyPassword does the same work as CheckPasswordHash without matching
+// any account, so that logins naming an unknown email take as long as logins
+// with a wrong password.
+func CheckDummyPassword(password string) {
+	CheckPasswordHash(password, dummyHash())
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	str, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
+type accessClaims struct {
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// MakeJWT signs an access token granting scopes with the key set's active key.
+func MakeJWT(userID uuid.UUID, scopes []string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := &accessClaims{
+		Scope: strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
 	}
 
-	return str, nil
+	return keys.sign(claims)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
-	})
+// ValidateJWT returns the user an access token was issued to and the scopes
+// it grants.
+func ValidateJWT(tokenString string, keys *KeySet) (uuid.UUID, []string, error) {
+	claims := &accessClaims{}
+	token, err := keys.parse(tokenString, claims)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
 	}
 
 	if !token.Valid {
-		return uuid.UUID{}, errors.New("invalid token")
+		return uuid.UUID{}, nil, errors.New("invalid token")
 	}
 
-	sub, err := token.Claims.GetSubject()
+	id, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
+	}
+
+	return id, strings.Fields(claims.Scope), nil
+}
+
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+const emailVerificationAudience = "chirpy-email-verification"
+
+// MakeEmailVerificationToken signs a token proving control of email. It uses
+// a key derived from tokenSecret so it can never pass as an access token.
+func MakeEmailVerificationToken(userID uuid.UUID, email, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claims := &emailVerificationClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(purposeKey(tokenSecret, emailVerificationAudience))
+}
+
+// ValidateEmailVerificationToken returns the user and email address a
+// verification token was issued for.
+func ValidateEmailVerificationToken(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+	claims := &emailVerificationClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		return purposeKey(tokenSecret, emailVerificationAudience), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(emailVerificationAudience))
+	if err != nil {
+		return uuid.UUID{}, "", err
 	}
 
-	id, err := uuid.Parse(sub)
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+
+	return id, claims.Email, nil
+}
+
+const twoFactorChallengeAudience = "chirpy-2fa-challenge"
+
+// MakeTwoFactorChallengeToken signs a token proving the password step of a
+// two-step login succeeded. Like verification tokens it uses a derived key.
+func MakeTwoFactorChallengeToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claims := &jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{twoFactorChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(purposeKey(tokenSecret, twoFactorChallengeAudience))
+}
+
+// ValidateTwoFactorChallengeToken returns the user a challenge was issued for.
+func ValidateTwoFactorChallengeToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		return purposeKey(tokenSecret, twoFactorChallengeAudience), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(twoFactorChallengeAudience))
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
-	return id, nil
+	return uuid.Parse(claims.Subject)
+}
+
+func purposeKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -84,6 +184,12 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	return MakeToken()
+}
+
+// MakeToken returns 32 random bytes as a hex string, suitable for opaque
+// single-use tokens sent to users.
+func MakeToken() (string, error) {
 	bytes := make([]byte, 32)
 	_, err := rand.Read(bytes)
 	if err != nil {
@@ -93,6 +199,28 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashToken hashes a high-entropy token for storage. Unlike passwords these
+// tokens cannot be brute forced, so a fast hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckRefreshToken reports whether a stored refresh token may be rotated.
+// A revoked token being presented again means it was already rotated or
+// logged out, so callers should treat ErrRefreshTokenReused as theft.
+func CheckRefreshToken(expiresAt time.Time, revoked bool) error {
+	if revoked {
+		return ErrRefreshTokenReused
+	}
+
+	if !time.Now().UTC().Before(expiresAt) {
+		return ErrRefreshTokenExpired
+	}
+
+	return nil
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	key := strings.Split(headers.Get("Authorization"), " ")
 	if len(key) != 2 || len(key) == 0 {
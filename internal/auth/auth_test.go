@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"testing"
 	"time"
@@ -24,33 +25,89 @@ func TestCheckHash(t *testing.T) {
 	}
 }
 
+func hmacKeySet(t *testing.T, secret string) *KeySet {
+	t.Helper()
+	keys, err := NewKeySet("test", NewHMACKey("test", []byte(secret)))
+	if err != nil {
+		t.Fatalf("failed to make key set: %v", err)
+	}
+
+	return keys
+}
+
 func TestMakeJWT(t *testing.T) {
-	token, err := MakeJWT(uuid.New(), "secret", 5*time.Minute)
+	token, err := MakeJWT(uuid.New(), AllScopes, hmacKeySet(t, "secret"), 5*time.Minute)
 	if err != nil || len(token) == 0 {
 		t.Error("failed to make jwt token")
 	}
 }
 
 func TestValidateJWT(t *testing.T) {
-	secret := "secret"
+	keys := hmacKeySet(t, "secret")
 	userID := uuid.New()
-	token, _ := MakeJWT(userID, secret, 5*time.Minute)
-	id, err := ValidateJWT(token, secret)
+	token, _ := MakeJWT(userID, AllScopes, keys, 5*time.Minute)
+	id, _, err := ValidateJWT(token, keys)
 	if err != nil || id.String() != userID.String() {
 		t.Error("failed to validate jwt")
 	}
 
-	if _, err := ValidateJWT(token, "wrong"); err == nil {
+	if _, _, err := ValidateJWT(token, hmacKeySet(t, "wrong")); err == nil {
 		t.Error("wrong secret should error")
 	}
 
-	expired, _ := MakeJWT(userID, secret, 4*time.Second)
+	expired, _ := MakeJWT(userID, AllScopes, keys, 4*time.Second)
 	time.Sleep(5 * time.Second)
-	if _, err := ValidateJWT(expired, secret); err == nil {
+	if _, _, err := ValidateJWT(expired, keys); err == nil {
 		t.Error("expired token should error")
 	}
 }
 
+func TestEmailVerificationToken(t *testing.T) {
+	secret := "secret"
+	userID := uuid.New()
+	token, err := MakeEmailVerificationToken(userID, "walt@breakingbad.com", secret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to make verification token: %v", err)
+	}
+
+	id, email, err := ValidateEmailVerificationToken(token, secret)
+	if err != nil || id != userID || email != "walt@breakingbad.com" {
+		t.Errorf("failed to validate verification token: %v", err)
+	}
+
+	if _, _, err := ValidateJWT(token, hmacKeySet(t, secret)); err == nil {
+		t.Error("verification token should not be accepted as an access token")
+	}
+
+	access, _ := MakeJWT(userID, AllScopes, hmacKeySet(t, secret), 5*time.Minute)
+	if _, _, err := ValidateEmailVerificationToken(access, secret); err == nil {
+		t.Error("access token should not be accepted as a verification token")
+	}
+}
+
+func TestTwoFactorChallengeToken(t *testing.T) {
+	secret := "secret"
+	userID := uuid.New()
+	token, err := MakeTwoFactorChallengeToken(userID, secret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to make challenge token: %v", err)
+	}
+
+	id, err := ValidateTwoFactorChallengeToken(token, secret)
+	if err != nil || id != userID {
+		t.Errorf("failed to validate challenge token: %v", err)
+	}
+
+	if _, _, err := ValidateJWT(token, hmacKeySet(t, secret)); err == nil {
+		t.Error("challenge token should not be accepted as an access token")
+	}
+
+	verification, _ := MakeEmailVerificationToken(userID, "walt@breakingbad.com", secret, 5*time.Minute)
+	if _, err := ValidateTwoFactorChallengeToken(verification, secret); err == nil {
+		t.Error("verification token should not be accepted as a challenge token")
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	headers := http.Header{}
 	headers.Set("Authorization", "Bearer my_token")
@@ -59,3 +116,49 @@ func TestGetBearerToken(t *testing.T) {
 		t.Error("failed to retrieve bearer token")
 	}
 }
+
+func TestMakeRefreshToken(t *testing.T) {
+	first, err := MakeRefreshToken()
+	if err != nil || len(first) != 64 {
+		t.Error("failed to make refresh token")
+	}
+
+	second, _ := MakeRefreshToken()
+	if first == second {
+		t.Error("refresh tokens should be unique")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token, _ := MakeToken()
+	if HashToken(token) != HashToken(token) {
+		t.Error("hashing should be deterministic")
+	}
+
+	if HashToken(token) == token || len(HashToken(token)) != 64 {
+		t.Error("failed to hash token")
+	}
+
+	other, _ := MakeToken()
+	if HashToken(token) == HashToken(other) {
+		t.Error("different tokens should have different hashes")
+	}
+}
+
+func TestCheckRefreshToken(t *testing.T) {
+	if err := CheckRefreshToken(time.Now().UTC().Add(time.Hour), false); err != nil {
+		t.Errorf("active token should pass: %v", err)
+	}
+
+	if err := CheckRefreshToken(time.Now().UTC().Add(time.Hour), true); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("revoked token should be reported as reused, got %v", err)
+	}
+
+	if err := CheckRefreshToken(time.Now().UTC().Add(-time.Hour), false); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Errorf("expired token should be reported as expired, got %v", err)
+	}
+
+	if err := CheckRefreshToken(time.Now().UTC().Add(-time.Hour), true); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("reuse should take precedence over expiry, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey      = errors.New("unknown signing key")
+	ErrNoSigningKey    = errors.New("no active signing key")
+	ErrUnsupportedKey  = errors.New("unsupported key type")
+	ErrAlgorithmDenied = errors.New("token algorithm does not match its key")
+)
+
+// Key is one entry of a KeySet. Keys without a private half can still verify
+// tokens, which is how retired keys are kept around after a rotation.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+// NewHMACKey returns an HS256 key. HMAC keys are never published in the JWKS.
+func NewHMACKey(kid string, secret []byte) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+// NewKey wraps an RSA or Ed25519 private key, picking RS256 or EdDSA.
+func NewKey(kid string, private crypto.Signer) (Key, error) {
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		return Key{ID: kid, Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return Key{ID: kid, Method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, private)
+	}
+}
+
+// NewPublicKey wraps an RSA or Ed25519 public key for verification only.
+func NewPublicKey(kid string, public crypto.PublicKey) (Key, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return Key{ID: kid, Method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PublicKey:
+		return Key{ID: kid, Method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+}
+
+// ParseKeyPEM reads a PKCS#8 or PKCS#1 private key, or a PKIX public key.
+func ParseKeyPEM(kid string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %s: no PEM block found", kid)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return Key{}, fmt.Errorf("key %s: %w", kid, ErrUnsupportedKey)
+		}
+
+		return NewKey(kid, signer)
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		return NewKey(kid, private)
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		return NewPublicKey(kid, public)
+	default:
+		return Key{}, fmt.Errorf("key %s: unexpected PEM block %q", kid, block.Type)
+	}
+}
+
+// KeySet signs access tokens with its active key and verifies them with
+// whichever key the token's kid header names.
+type KeySet struct {
+	active string
+	keys   map[string]Key
+}
+
+// NewKeySet builds a key set signing with the key named active, which must
+// have a private half.
+func NewKeySet(active string, keys ...Key) (*KeySet, error) {
+	ks := &KeySet{active: active, keys: map[string]Key{}}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	if key, ok := ks.keys[active]; !ok || key.private == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return ks, nil
+}
+
+// LoadKeySet reads every *.pem file in dir, using the file name as the kid.
+// If active is empty the directory must hold exactly one private key.
+func LoadKeySet(dir, active string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []Key{}
+	signers := []string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParseKeyPEM(kid, data)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		if key.private != nil {
+			signers = append(signers, kid)
+		}
+	}
+
+	if active == "" {
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("%w: found %d private keys, set the active key id", ErrNoSigningKey, len(signers))
+		}
+		active = signers[0]
+	}
+
+	return NewKeySet(active, keys...)
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[ks.active]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// parse verifies a token against the key its kid names. The algorithm must
+// be exactly the one that key was made for, so an RSA public key can never
+// be used as an HMAC secret and "none" is never accepted.
+func (ks *KeySet) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, ErrAlgorithmDenied
+		}
+
+		return key.public, nil
+	}, jwt.WithValidMethods(ks.algorithms()), jwt.WithIssuer("chirpy"))
+}
+
+func (ks *KeySet) algorithms() []string {
+	algs := []string{}
+	for _, key := range ks.keys {
+		if !slices.Contains(algs, key.Method.Alg()) {
+			algs = append(algs, key.Method.Alg())
+		}
+	}
+
+	return algs
+}
+
+// JWK is the public half of a key in RFC 7517 format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services need to verify access tokens,
+// sorted by kid. Symmetric keys are secret and left out.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+
+	return set
+}
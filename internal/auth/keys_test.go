@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func rsaKey(t *testing.T, kid string) (Key, *rsa.PrivateKey) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %v", err)
+	}
+
+	key, err := NewKey(kid, private)
+	if err != nil {
+		t.Fatalf("failed to wrap rsa key: %v", err)
+	}
+
+	return key, private
+}
+
+func ed25519Key(t *testing.T, kid string) (Key, ed25519.PrivateKey) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	key, err := NewKey(kid, private)
+	if err != nil {
+		t.Fatalf("failed to wrap ed25519 key: %v", err)
+	}
+
+	return key, private
+}
+
+func TestKeySetSignsWithActiveKey(t *testing.T) {
+	rsaSigner, _ := rsaKey(t, "rsa-1")
+	edSigner, _ := ed25519Key(t, "ed-1")
+
+	for _, active := range []Key{rsaSigner, edSigner} {
+		keys, err := NewKeySet(active.ID, rsaSigner, edSigner)
+		if err != nil {
+			t.Fatalf("failed to make key set: %v", err)
+		}
+
+		userID := uuid.New()
+		token, err := MakeJWT(userID, AllScopes, keys, 5*time.Minute)
+		if err != nil {
+			t.Fatalf("failed to sign with %s: %v", active.ID, err)
+		}
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+		if err != nil || parsed.Header["kid"] != active.ID || parsed.Method.Alg() != active.Method.Alg() {
+			t.Errorf("expected kid %s with %s, got %v", active.ID, active.Method.Alg(), parsed.Header)
+		}
+
+		id, _, err := ValidateJWT(token, keys)
+		if err != nil || id != userID {
+			t.Errorf("failed to validate token signed by %s: %v", active.ID, err)
+		}
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	old, oldPrivate := rsaKey(t, "old")
+	current, _ := ed25519Key(t, "new")
+
+	before, _ := NewKeySet("old", old)
+	token, _ := MakeJWT(uuid.New(), AllScopes, before, 5*time.Minute)
+
+	// after rotating, only the public half of the old key is kept
+	retired, _ := NewPublicKey("old", &oldPrivate.PublicKey)
+	after, err := NewKeySet("new", current, retired)
+	if err != nil {
+		t.Fatalf("failed to make rotated key set: %v", err)
+	}
+
+	if _, _, err := ValidateJWT(token, after); err != nil {
+		t.Errorf("token signed before rotation should still validate: %v", err)
+	}
+
+	if _, err := NewKeySet("old", current, retired); !errors.Is(err, ErrNoSigningKey) {
+		t.Error("a public key should not be usable as the active key")
+	}
+
+	dropped, _ := NewKeySet("new", current)
+	if _, _, err := ValidateJWT(token, dropped); err == nil {
+		t.Error("token signed by a removed key should be rejected")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	key, private := rsaKey(t, "rsa-1")
+	keys, _ := NewKeySet("rsa-1", key, NewHMACKey("hmac-1", []byte("secret")))
+	claims := &jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Subject:   uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+
+	// an HS256 token "signed" with the RSA public key, claiming the RSA kid
+	publicDER, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	confused.Header["kid"] = "rsa-1"
+	token, _ := confused.SignedString(publicDER)
+	if _, _, err := ValidateJWT(token, keys); err == nil {
+		t.Error("HS256 token naming an RSA key should be rejected")
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	unsigned.Header["kid"] = "rsa-1"
+	token, _ = unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, _, err := ValidateJWT(token, keys); err == nil {
+		t.Error("unsigned token should be rejected")
+	}
+
+	missing := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token, _ = missing.SignedString(private)
+	if _, _, err := ValidateJWT(token, keys); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token without a kid should be rejected, got %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaSigner, _ := rsaKey(t, "a-rsa")
+	edSigner, _ := ed25519Key(t, "b-ed")
+	keys, _ := NewKeySet("a-rsa", rsaSigner, edSigner, NewHMACKey("c-hmac", []byte("secret")))
+
+	set := keys.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(set.Keys))
+	}
+
+	if k := set.Keys[0]; k.KeyID != "a-rsa" || k.KeyType != "RSA" || k.Algorithm != "RS256" || k.N == "" || k.E != "AQAB" {
+		t.Errorf("unexpected rsa jwk %+v", k)
+	}
+
+	if k := set.Keys[1]; k.KeyID != "b-ed" || k.KeyType != "OKP" || k.Curve != "Ed25519" || k.Algorithm != "EdDSA" || len(k.X) != 43 {
+		t.Errorf("unexpected ed25519 jwk %+v", k)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	_, edPrivate := ed25519Key(t, "")
+	_, rsaPrivate := rsaKey(t, "")
+
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	rsaDER, _ := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	write := func(name, blockType string, der []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+	}
+
+	write("2026-10.pem", "PRIVATE KEY", edDER)
+	write("2026-04.pem", "PUBLIC KEY", rsaDER)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("failed to load key set: %v", err)
+	}
+
+	token, _ := MakeJWT(uuid.New(), AllScopes, keys, time.Minute)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	if parsed.Header["kid"] != "2026-10" {
+		t.Errorf("expected the only private key to be active, got %v", parsed.Header["kid"])
+	}
+
+	if len(keys.JWKS().Keys) != 2 {
+		t.Error("retired public key should be published")
+	}
+
+	if _, err := LoadKeySet(dir, "2026-04"); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("public key should not be selectable as active, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeFollowsWrite = "follows:write"
+	ScopeTimelineRead = "timeline:read"
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+
+	// ScopeAccount covers sessions, tokens, two-factor settings, data export
+	// and deletion. Only tokens from an interactive login carry it.
+	ScopeAccount = "account"
+)
+
+// AllScopes is granted to access tokens issued by logging in.
+var AllScopes = []string{
+	ScopeChirpsWrite,
+	ScopeFollowsWrite,
+	ScopeTimelineRead,
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeAccount,
+}
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs, and found by secret scanners if leaked.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+var (
+	ErrInsufficientScope = errors.New("token does not grant the required scope")
+	ErrInvalidScope      = errors.New("invalid scope")
+)
+
+// HasScope reports whether granted includes scope. An empty scope is always
+// granted, for routes that only need to know who is calling.
+func HasScope(granted []string, scope string) bool {
+	return scope == "" || slices.Contains(granted, scope)
+}
+
+// ParseDelegableScopes validates scopes requested for a personal access token
+// and returns them deduplicated in canonical order. ScopeAccount cannot be
+// delegated, so a leaked token can never take over the account.
+func ParseDelegableScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	for _, scope := range requested {
+		if scope == ScopeAccount || !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	scopes := []string{}
+	for _, scope := range AllScopes {
+		if slices.Contains(requested, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
+
+// MakePersonalAccessToken returns a new personal access token. Only its
+// HashToken hash should be stored.
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeToken()
+	if err != nil {
+		return "", err
+	}
+
+	return PersonalAccessTokenPrefix + token, nil
+}
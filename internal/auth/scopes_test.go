@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestJWTScopes(t *testing.T) {
+	keys := hmacKeySet(t, "secret")
+	token, _ := MakeJWT(uuid.New(), []string{ScopeChirpsWrite, ScopeProfileRead}, keys, 5*time.Minute)
+
+	_, scopes, err := ValidateJWT(token, keys)
+	if err != nil {
+		t.Fatalf("failed to validate jwt: %v", err)
+	}
+
+	if !HasScope(scopes, ScopeChirpsWrite) || !HasScope(scopes, ScopeProfileRead) {
+		t.Errorf("expected granted scopes, got %v", scopes)
+	}
+
+	if HasScope(scopes, ScopeAccount) {
+		t.Error("scope that was not granted should be missing")
+	}
+
+	if !HasScope(nil, "") {
+		t.Error("empty scope should always be granted")
+	}
+}
+
+func TestParseDelegableScopes(t *testing.T) {
+	scopes, err := ParseDelegableScopes([]string{ScopeProfileRead, ScopeChirpsWrite, ScopeProfileRead})
+	if err != nil {
+		t.Fatalf("failed to parse scopes: %v", err)
+	}
+
+	if !slices.Equal(scopes, []string{ScopeChirpsWrite, ScopeProfileRead}) {
+		t.Errorf("expected deduplicated canonical order, got %v", scopes)
+	}
+
+	for _, requested := range [][]string{nil, {ScopeAccount}, {"chirps:delete"}, {ScopeChirpsWrite, ""}} {
+		if _, err := ParseDelegableScopes(requested); !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("%v should be rejected", requested)
+		}
+	}
+}
+
+func TestMakePersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil || !strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		t.Errorf("failed to make personal access token: %v", err)
+	}
+
+	other, _ := MakePersonalAccessToken()
+	if token == other {
+		t.Error("personal access tokens should be unique")
+	}
+}
@@ -0,0 +1,51 @@
+// Package backoff decides how long a client has to wait after repeated
+// failures, such as wrong passwords, before it may try again.
+package backoff
+
+import "time"
+
+// Policy delays retries exponentially once the free attempts are used up and
+// locks the client out entirely after too many failures.
+type Policy struct {
+	// Free is how many failures are allowed before any delay applies.
+	Free int
+	// Base is the delay after the first failure past Free. It doubles with
+	// each further failure, up to Max.
+	Base time.Duration
+	Max  time.Duration
+
+	LockoutAfter    int
+	LockoutDuration time.Duration
+}
+
+// Delay returns how long to wait after the given number of failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockedOut(failures) {
+		return p.LockoutDuration
+	}
+
+	if failures <= p.Free {
+		return 0
+	}
+
+	delay := p.Base
+	for range failures - p.Free - 1 {
+		if delay >= p.Max {
+			break
+		}
+		delay *= 2
+	}
+
+	return min(delay, p.Max)
+}
+
+// LockedOut reports whether failures is enough for a lockout.
+func (p Policy) LockedOut(failures int) bool {
+	return p.LockoutAfter > 0 && failures >= p.LockoutAfter
+}
+
+// RetryAfter returns how long from now the client must still wait, or zero
+// if it may try again.
+func (p Policy) RetryAfter(failures int, lastFailure, now time.Time) time.Duration {
+	return max(lastFailure.Add(p.Delay(failures)).Sub(now), 0)
+}
@@ -0,0 +1,59 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{Free: 3, Base: time.Second, Max: 30 * time.Second, LockoutAfter: 10, LockoutDuration: 15 * time.Minute}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{8, 16 * time.Second},
+		{9, 30 * time.Second},
+		{10, 15 * time.Minute},
+		{1000, 15 * time.Minute},
+	}
+
+	for _, c := range cases {
+		if got := p.Delay(c.failures); got != c.want {
+			t.Errorf("Delay(%d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+
+	if p.LockedOut(9) || !p.LockedOut(10) {
+		t.Error("lockout should start at 10 failures")
+	}
+}
+
+func TestDelayWithoutLockout(t *testing.T) {
+	p := Policy{Base: time.Second, Max: time.Hour}
+	if p.LockedOut(1 << 20) {
+		t.Error("a zero LockoutAfter should never lock out")
+	}
+
+	if got := p.Delay(1 << 20); got != time.Hour {
+		t.Errorf("delay should be capped at max, got %v", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	p := Policy{Free: 0, Base: 10 * time.Second, Max: time.Minute}
+	last := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	if got := p.RetryAfter(1, last, last.Add(4*time.Second)); got != 6*time.Second {
+		t.Errorf("expected 6s left, got %v", got)
+	}
+
+	if got := p.RetryAfter(1, last, last.Add(time.Minute)); got != 0 {
+		t.Errorf("expected no wait once the delay has passed, got %v", got)
+	}
+}
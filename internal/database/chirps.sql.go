@@ -7,22 +7,31 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (body, user_id)
-VALUES ($1, $2) RETURNING id, body, user_id, created_at, updated_at
+INSERT INTO chirps (body, user_id, reply_to_id, quoted_chirp_id)
+VALUES ($1, $2, $3, $4) RETURNING id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ReplyToID     uuid.NullUUID `json:"reply_to_id"`
+	QuotedChirpID uuid.NullUUID `json:"quoted_chirp_id"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.QuotedChirpID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -30,6 +39,41 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ReplyToID,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (body, user_id, rechirp_of_id)
+VALUES ('', $1, $2)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at
+`
+
+type CreateRechirpParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReplyToID,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -43,12 +87,109 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id = $1
+AND rechirp_of_id = COALESCE(
+  (SELECT r.rechirp_of_id FROM chirps r WHERE r.id = $2),
+  $2::uuid
+)
+`
+
+type DeleteRechirpParams struct {
+	UserID  uuid.UUID `json:"user_id"`
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+// chirp_id may be a rechirp, which stands for its original like it does when
+// rechirping. It isn't checked for visibility, so a rechirp can still be
+// undone after its original is hidden or its author blocks the user.
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT p.id, p.reply_to_id, 1 AS depth FROM chirps p
+  WHERE p.id = (SELECT r.reply_to_id FROM chirps r WHERE r.id = $1)
+  UNION ALL
+  SELECT p.id, p.reply_to_id, a.depth + 1 FROM chirps p
+  INNER JOIN ancestors a ON p.id = a.reply_to_id
+)
+SELECT c.id, c.body, c.user_id, c.created_at, c.updated_at, c.reply_to_id, c.rechirp_of_id, c.quoted_chirp_id, c.search_vector, c.hidden_at FROM chirps c
+INNER JOIN ancestors a ON a.id = c.id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.deleted_at IS NOT NULL)
+AND (c.hidden_at IS NULL OR c.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = c.user_id)
+  OR (b.blocker_id = c.user_id AND b.blocked_id = $2))
+ORDER BY a.depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID     `json:"chirp_id"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, body, user_id, created_at, updated_at FROM chirps WHERE id = $1
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps
+WHERE id = $1
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
 `
 
-func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpByID, id)
+type GetChirpByIDParams struct {
+	ID       uuid.UUID     `json:"id"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+}
+
+// Hidden chirps are only found by their author and by moderators, and
+// chirps are never found across a block.
+func (q *Queries) GetChirpByID(ctx context.Context, arg GetChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByID, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -56,16 +197,186 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ReplyToID,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, body, user_id, created_at, updated_at FROM chirps ORDER BY created_at
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1)
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
+AND ($1::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $2
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = $2
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = $2))
+))
+AND ($3::timestamp IS NULL
+  OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at, id
+LIMIT $5
+`
+
+type GetChirpsParams struct {
+	AuthorID       uuid.NullUUID `json:"author_id"`
+	ViewerID       uuid.NullUUID `json:"viewer_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	Limit          int32         `json:"limit"`
+}
+
+// Rechirps are left out when the viewer can't see the rechirped chirp, so
+// no empty rechirp rows point at hidden or blocked chirps.
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps
+WHERE id = ANY($1::uuid[]) AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID   `json:"ids"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsDesc = `-- name: GetChirpsDesc :many
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1)
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
+AND ($1::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $2
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = $2
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = $2))
+))
+AND ($3::timestamp IS NULL
+  OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsDescParams struct {
+	AuthorID       uuid.NullUUID `json:"author_id"`
+	ViewerID       uuid.NullUUID `json:"viewer_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	Limit          int32         `json:"limit"`
+}
+
+func (q *Queries) GetChirpsDesc(ctx context.Context, arg GetChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDesc,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +390,11 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -93,13 +409,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByAuthorID = `-- name: GetChirpsByAuthorID :many
-SELECT id, body, user_id, created_at, updated_at FROM chirps WHERE user_id = $1
-ORDER BY created_at
+const getChirpsForExport = `-- name: GetChirpsForExport :many
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps WHERE user_id = $1
+ORDER BY created_at, id
 `
 
-func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorID, userID)
+func (q *Queries) GetChirpsForExport(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsForExport, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +429,11 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -126,3 +447,213 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 	}
 	return items, nil
 }
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2
+`
+
+type GetRechirpParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReplyToID,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getReplies = `-- name: GetReplies :many
+SELECT id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at FROM chirps
+WHERE reply_to_id = $1
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $2 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
+AND ($3::timestamp IS NULL
+  OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at, id
+LIMIT $5
+`
+
+type GetRepliesParams struct {
+	ChirpID        uuid.UUID     `json:"chirp_id"`
+	ViewerID       uuid.NullUUID `json:"viewer_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	Limit          int32         `json:"limit"`
+}
+
+func (q *Queries) GetReplies(ctx context.Context, arg GetRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getReplies,
+		arg.ChirpID,
+		arg.ViewerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyToID,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.reply_to_id, chirps.rechirp_of_id, chirps.quoted_chirp_id, chirps.search_vector, chirps.hidden_at,
+CAST(ts_rank(chirps.search_vector, websearch_to_tsquery('english', $1)) AS REAL) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ websearch_to_tsquery('english', $1)
+AND ($2::uuid IS NULL OR chirps.user_id = $2)
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $3
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = $3 AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = $3 AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = $3))
+AND ($2::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $3
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = $3
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = $3 AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $3 AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = $3))
+))
+AND ($4::real IS NULL
+  OR (CAST(ts_rank(chirps.search_vector, websearch_to_tsquery('english', $1)) AS REAL), chirps.created_at, chirps.id)
+  < ($4::real, $5::timestamp, $6::uuid))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $7
+`
+
+type SearchChirpsParams struct {
+	Query          string          `json:"query"`
+	AuthorID       uuid.NullUUID   `json:"author_id"`
+	ViewerID       uuid.NullUUID   `json:"viewer_id"`
+	AfterRank      sql.NullFloat64 `json:"after_rank"`
+	AfterCreatedAt sql.NullTime    `json:"after_created_at"`
+	AfterID        uuid.NullUUID   `json:"after_id"`
+	Limit          int32           `json:"limit"`
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp   `json:"chirp"`
+	Rank  float32 `json:"rank"`
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.AfterRank,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.ReplyToID,
+			&i.Chirp.RechirpOfID,
+			&i.Chirp.QuotedChirpID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.HiddenAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpHidden = `-- name: SetChirpHidden :one
+UPDATE chirps
+SET hidden_at = CASE WHEN $1::boolean THEN COALESCE(hidden_at, NOW()) END
+WHERE id = $2
+RETURNING id, body, user_id, created_at, updated_at, reply_to_id, rechirp_of_id, quoted_chirp_id, search_vector, hidden_at
+`
+
+type SetChirpHiddenParams struct {
+	Hidden bool      `json:"hidden"`
+	ID     uuid.UUID `json:"id"`
+}
+
+func (q *Queries) SetChirpHidden(ctx context.Context, arg SetChirpHiddenParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpHidden, arg.Hidden, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReplyToID,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
@@ -11,28 +11,196 @@ import (
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    uuid.NullUUID `json:"user_id"`
+	Action    string        `json:"action"`
+	IpAddress string        `json:"ip_address"`
+	Detail    string        `json:"detail"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Chirp struct {
+	ID            uuid.UUID     `json:"id"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	ReplyToID     uuid.NullUUID `json:"reply_to_id"`
+	RechirpOfID   uuid.NullUUID `json:"rechirp_of_id"`
+	QuotedChirpID uuid.NullUUID `json:"quoted_chirp_id"`
+	SearchVector  interface{}   `json:"search_vector"`
+	HiddenAt      sql.NullTime  `json:"hidden_at"`
+}
+
+type ChirpFlag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Words     []string  `json:"words"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Key           string    `json:"key"`
+	Failures      int32     `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+}
+
+type ModerationList struct {
 	ID        uuid.UUID `json:"id"`
-	Body      string    `json:"body"`
+	Name      string    `json:"name"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ModerationWord struct {
+	ListID    uuid.UUID `json:"list_id"`
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string       `json:"code_hash"`
+	ClientID      uuid.UUID    `json:"client_id"`
+	UserID        uuid.UUID    `json:"user_id"`
+	RedirectUri   string       `json:"redirect_uri"`
+	Scopes        []string     `json:"scopes"`
+	CodeChallenge string       `json:"code_challenge"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	UsedAt        sql.NullTime `json:"used_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID           uuid.UUID      `json:"id"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	Name         string         `json:"name"`
+	RedirectUris []string       `json:"redirect_uris"`
+	SecretHash   sql.NullString `json:"secret_hash"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type OauthConsent struct {
 	UserID    uuid.UUID `json:"user_id"`
+	ClientID  uuid.UUID `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type RefreshToken struct {
-	Token     string       `json:"token"`
+type PasswordResetToken struct {
+	TokenHash string       `json:"token_hash"`
 	UserID    uuid.UUID    `json:"user_id"`
 	ExpiresAt time.Time    `json:"expires_at"`
-	RevokedAt sql.NullTime `json:"revoked_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     []string     `json:"scopes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	CodeHash  string       `json:"code_hash"`
+	UserID    uuid.UUID    `json:"user_id"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RefreshToken struct {
+	Token       string         `json:"token"`
+	UserID      uuid.UUID      `json:"user_id"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	RevokedAt   sql.NullTime   `json:"revoked_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	FamilyID    uuid.UUID      `json:"family_id"`
+	ParentToken sql.NullString `json:"parent_token"`
+	UserAgent   string         `json:"user_agent"`
+	IpAddress   string         `json:"ip_address"`
+	LastUsedAt  time.Time      `json:"last_used_at"`
+	ClientID    uuid.NullUUID  `json:"client_id"`
+	Scopes      []string       `json:"scopes"`
+}
+
+type Report struct {
+	ID         uuid.UUID    `json:"id"`
+	ChirpID    uuid.UUID    `json:"chirp_id"`
+	ReporterID uuid.UUID    `json:"reporter_id"`
+	Reason     string       `json:"reason"`
+	Details    string       `json:"details"`
+	Status     string       `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ClosedAt   sql.NullTime `json:"closed_at"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	Email          string    `json:"email"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	HashedPassword  string         `json:"hashed_password"`
+	IsChirpyRed     bool           `json:"is_chirpy_red"`
+	Handle          sql.NullString `json:"handle"`
+	DisplayName     string         `json:"display_name"`
+	Bio             string         `json:"bio"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+	DeletedAt       sql.NullTime   `json:"deleted_at"`
+	TotpSecret      sql.NullString `json:"totp_secret"`
+	TotpEnabledAt   sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep    sql.NullInt64  `json:"totp_last_step"`
+	SuspendedAt     sql.NullTime   `json:"suspended_at"`
+	SuspendedUntil  sql.NullTime   `json:"suspended_until"`
+	Role            string         `json:"role"`
 }
@@ -11,44 +11,146 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const consumeRefreshToken = `-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) ConsumeRefreshToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, consumeRefreshToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :exec
-INSERT INTO refresh_tokens (token, user_id, expires_at)
-VALUES ( $1, $2, $3)
+INSERT INTO refresh_tokens (token, user_id, expires_at, family_id, parent_token, user_agent, ip_address, client_id, scopes)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9)
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string    `json:"token"`
-	UserID    uuid.UUID `json:"user_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token       string         `json:"token"`
+	UserID      uuid.UUID      `json:"user_id"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	FamilyID    uuid.UUID      `json:"family_id"`
+	ParentToken sql.NullString `json:"parent_token"`
+	UserAgent   string         `json:"user_agent"`
+	IpAddress   string         `json:"ip_address"`
+	ClientID    uuid.NullUUID  `json:"client_id"`
+	Scopes      []string       `json:"scopes"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, createRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.ParentToken,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ClientID,
+		pq.Array(arg.Scopes),
+	)
 	return err
 }
 
-const getUserByRefreshToken = `-- name: GetUserByRefreshToken :one
-SELECT u.id, u.email, u.created_at, u.updated_at, u.hashed_password, u.is_chirpy_red FROM refresh_tokens t
-INNER JOIN users u ON u.id = t.user_id
-WHERE t.token = $1
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT token, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token, user_agent, ip_address, last_used_at, client_id, scopes FROM refresh_tokens WHERE token = $1
 `
 
-func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByRefreshToken, token)
-	var i User
+func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, token)
+	var i RefreshToken
 	err := row.Scan(
-		&i.ID,
-		&i.Email,
+		&i.Token,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
+const getSessionsByUserID = `-- name: GetSessionsByUserID :many
+SELECT t.family_id, t.user_agent, t.ip_address, t.last_used_at,
+CAST((SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) AS TIMESTAMP) AS started_at
+FROM refresh_tokens t
+WHERE t.user_id = $1 AND t.revoked_at IS NULL AND t.expires_at > $2
+ORDER BY t.last_used_at DESC
+`
+
+type GetSessionsByUserIDParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Now    time.Time `json:"now"`
+}
+
+type GetSessionsByUserIDRow struct {
+	FamilyID   uuid.UUID `json:"family_id"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+func (q *Queries) GetSessionsByUserID(ctx context.Context, arg GetSessionsByUserIDParams) ([]GetSessionsByUserIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSessionsByUserID, arg.UserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSessionsByUserIDRow
+	for rows.Next() {
+		var i GetSessionsByUserIDRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
+			&i.StartedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeClientRefreshTokens = `-- name: RevokeClientRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeClientRefreshTokensParams struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	ClientID uuid.NullUUID `json:"client_id"`
+}
+
+func (q *Queries) RevokeClientRefreshTokens(ctx context.Context, arg RevokeClientRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeClientRefreshTokens, arg.UserID, arg.ClientID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $2, updated_at = $3
@@ -65,3 +167,44 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, arg RevokeRefreshToken
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, arg.Token, arg.RevokedAt, arg.UpdatedAt)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID `json:"family_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
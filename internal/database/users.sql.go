@@ -7,39 +7,45 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
-VALUES ($1, $2)
-RETURNING id, email, created_at, updated_at, is_chirpy_red
+INSERT INTO users (email, hashed_password, handle)
+VALUES ($1, $2, $3)
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role
 `
 
 type CreateUserParams struct {
-	Email          string `json:"email"`
-	HashedPassword string `json:"hashed_password"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	Handle         sql.NullString `json:"handle"`
 }
 
-type CreateUserRow struct {
-	ID          uuid.UUID `json:"id"`
-	Email       string    `json:"email"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
-}
-
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
-	var i CreateUserRow
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
+	var i User
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
 	)
 	return i, err
 }
@@ -53,8 +59,35 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type EnableUserTOTPParams struct {
+	ID           uuid.UUID     `json:"id"`
+	TotpLastStep sql.NullInt64 `json:"totp_last_step"`
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.TotpLastStep)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -67,10 +100,145 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
 	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role FROM users WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserForLogin = `-- name: GetUserForLogin :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role FROM users WHERE id = $1
+`
+
+// Unlike GetUserByID this includes accounts pending deletion, since logging
+// in restores them.
+func (q *Queries) GetUserForLogin(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForLogin, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserProfileByHandle = `-- name: GetUserProfileByHandle :one
+SELECT u.id, u.handle, u.display_name, u.bio, u.created_at, u.is_chirpy_red,
+(SELECT COUNT(*) FROM chirps c
+  WHERE c.user_id = u.id AND c.hidden_at IS NULL AND c.rechirp_of_id IS NULL) AS chirp_count,
+(SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) AS follower_count,
+(SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id) AS following_count
+FROM users u
+WHERE LOWER(u.handle) = LOWER($1) AND u.deleted_at IS NULL
+`
+
+type GetUserProfileByHandleRow struct {
+	ID             uuid.UUID      `json:"id"`
+	Handle         sql.NullString `json:"handle"`
+	DisplayName    string         `json:"display_name"`
+	Bio            string         `json:"bio"`
+	CreatedAt      time.Time      `json:"created_at"`
+	IsChirpyRed    bool           `json:"is_chirpy_red"`
+	ChirpCount     int64          `json:"chirp_count"`
+	FollowerCount  int64          `json:"follower_count"`
+	FollowingCount int64          `json:"following_count"`
+}
+
+// chirp_count only counts the user's own visible chirps; hidden chirps and rechirps are left out.
+func (q *Queries) GetUserProfileByHandle(ctx context.Context, handle string) (GetUserProfileByHandleRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfileByHandle, handle)
+	var i GetUserProfileByHandleRow
+	err := row.Scan(
+		&i.ID,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.IsChirpyRed,
+		&i.ChirpCount,
+		&i.FollowerCount,
+		&i.FollowingCount,
+	)
+	return i, err
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < $1::timestamp
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedUsers, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, restoreUser, id)
+	return err
+}
+
 const setUserChirpyRed = `-- name: SetUserChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = $2
@@ -87,25 +255,140 @@ func (q *Queries) SetUserChirpyRed(ctx context.Context, arg SetUserChirpyRedPara
 	return err
 }
 
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID `json:"id"`
+	Role string    `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         uuid.UUID      `json:"id"`
+	TotpSecret sql.NullString `json:"totp_secret"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :exec
+UPDATE users
+SET deleted_at = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SoftDeleteUserParams struct {
+	ID        uuid.UUID    `json:"id"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+// deleted_at comes from Go, in UTC like the cutoff PurgeDeletedUsers is given.
+func (q *Queries) SoftDeleteUser(ctx context.Context, arg SoftDeleteUserParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteUser, arg.ID, arg.DeletedAt)
+	return err
+}
+
+const suspendUser = `-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), suspended_until = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SuspendUserParams struct {
+	ID             uuid.UUID    `json:"id"`
+	SuspendedUntil sql.NullTime `json:"suspended_until"`
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NOT NULL
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsuspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET email = $2, hashed_password = $3, updated_at = $4
-WHERE id = $1 RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+SET email_verified_at = CASE
+  WHEN $1::text IS NULL OR $1 = email THEN email_verified_at
+END,
+email = COALESCE($1, email),
+hashed_password = COALESCE($2, hashed_password),
+handle = COALESCE($3, handle),
+display_name = COALESCE($4, display_name),
+bio = COALESCE($5, bio),
+updated_at = NOW()
+WHERE id = $6 RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, deleted_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, role
 `
 
 type UpdateUserParams struct {
-	ID             uuid.UUID `json:"id"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	Email          sql.NullString `json:"email"`
+	HashedPassword sql.NullString `json:"hashed_password"`
+	Handle         sql.NullString `json:"handle"`
+	DisplayName    sql.NullString `json:"display_name"`
+	Bio            sql.NullString `json:"bio"`
+	ID             uuid.UUID      `json:"id"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, updateUser,
-		arg.ID,
 		arg.Email,
 		arg.HashedPassword,
-		arg.UpdatedAt,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.ID,
 	)
 	var i User
 	err := row.Scan(
@@ -115,6 +398,55 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.Role,
 	)
 	return i, err
 }
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $1::bigint
+WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1::bigint)
+`
+
+type UseUserTOTPStepParams struct {
+	Step int64     `json:"step"`
+	ID   uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.Step, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "noreply@chirpy.io"}
+	msg := Message{To: "walt@breakingbad.com", Subject: "Hello", Body: "line one\nline two"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"From: noreply@chirpy.io\r\n", "To: walt@breakingbad.com\r\n", "Subject: Hello\r\n", "\r\n\r\nline one\r\nline two"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message is missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestFormatStripsHeaderInjection(t *testing.T) {
+	data := string(format("noreply@chirpy.io", Message{To: "a@b.io", Subject: "Hi\r\nBcc: evil@example.com"}))
+	if strings.Contains(data, "\r\nBcc:") {
+		t.Errorf("subject should not inject headers:\n%s", data)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &LogMailer{Logger: log.New(&buf, "", 0)}
+	if err := m.Send(context.Background(), Message{To: "walt@breakingbad.com", Subject: "Hello", Body: "hi"}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "walt@breakingbad.com") || !strings.Contains(buf.String(), "hi") {
+		t.Errorf("unexpected log output: %q", buf.String())
+	}
+}
@@ -0,0 +1,364 @@
+// Package moderation matches text against word lists. Matching is done on
+// whole words after folding case, accents, lookalike letters and leetspeak,
+// so "K3rfüffle!" matches "kerfuffle" but "kerfufflement" does not.
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Action string
+
+const (
+	// Mask replaces the word with asterisks.
+	Mask Action = "mask"
+	// Reject refuses the text outright.
+	Reject Action = "reject"
+	// Flag lets the text through unchanged but queues it for review.
+	Flag Action = "flag"
+)
+
+func (a Action) Valid() bool {
+	return a == Mask || a == Reject || a == Flag
+}
+
+const MaxWordLength = 50
+
+var (
+	ErrInvalidWord   = fmt.Errorf("words must be 1-%d letters", MaxWordLength)
+	ErrInvalidAction = errors.New("action must be mask, reject or flag")
+)
+
+// List is a named set of words sharing an action.
+type List struct {
+	Name   string   `json:"name"`
+	Action Action   `json:"action"`
+	Words  []string `json:"words"`
+}
+
+// Match is a listed word found in a text. Start and End are byte offsets of
+// the matching run in the original text.
+type Match struct {
+	Word   string
+	List   string
+	Action Action
+	Start  int
+	End    int
+}
+
+// lookalikes lists, for each letter, the characters that may stand in for
+// it: accented forms, Cyrillic and Greek homoglyphs and leetspeak.
+var lookalikes = map[rune]string{
+	'a': "àáâãäåāăąǎаα@4",
+	'b': "вβ8",
+	'c': "çćĉċčс¢",
+	'd': "ďđ",
+	'e': "èéêëēĕėęěеε€3",
+	'g': "ĝğġģ9",
+	'h': "ĥħн",
+	'i': "ìíîïĩīĭįıіι!|1",
+	'j': "ĵј",
+	'k': "ķкκ",
+	'l': "ĺļľŀł|1",
+	'm': "м",
+	'n': "ñńņňη",
+	'o': "òóôõöøōŏőоο0",
+	'p': "рρ",
+	'r': "ŕŗř",
+	's': "śŝşšѕ$5",
+	't': "ţťŧτ+7",
+	'u': "ùúûüũūŭůűų",
+	'x': "хχ",
+	'y': "ýÿŷуγ",
+	'z': "źżž",
+}
+
+// folds maps each lookalike character to the letters it may stand for.
+var folds = func() map[rune]string {
+	folds := map[rune]string{}
+	for letter, chars := range lookalikes {
+		for _, c := range chars {
+			folds[c] += string(letter)
+		}
+	}
+
+	for c, letters := range folds {
+		b := []byte(letters)
+		slices.Sort(b)
+		folds[c] = string(b)
+	}
+
+	return folds
+}()
+
+type runeKind int
+
+const (
+	separator runeKind = iota
+	letter
+	// symbol is punctuation that reads as a letter inside a word, like the
+	// "$" in "$hit", but is just punctuation at either end of one.
+	symbol
+	// ignorable runes are invisible: combining accents, zero-width spaces and
+	// soft hyphens. They neither break a word nor count as a letter.
+	ignorable
+)
+
+// classify folds r to the letters it may stand for.
+func classify(r rune) (runeKind, string) {
+	r = unicode.ToLower(r)
+	if r >= 0xFF01 && r <= 0xFF5E {
+		// fullwidth forms of ASCII
+		r = unicode.ToLower(r - 0xFEE0)
+	}
+
+	switch r {
+	case 0x00AD, 0x200B, 0x200C, 0x200D, 0x2060, 0xFEFF:
+		return ignorable, ""
+	}
+
+	if unicode.Is(unicode.Mn, r) {
+		return ignorable, ""
+	}
+
+	letters, ok := folds[r]
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		if !ok {
+			letters = string(r)
+		}
+		return letter, letters
+	case ok:
+		return symbol, letters
+	default:
+		return separator, ""
+	}
+}
+
+// NormalizeWord folds a word for storage in a list, so that "Café" and
+// "cafe" are the same entry. Only letters are allowed.
+func NormalizeWord(word string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(word) {
+		kind, letters := classify(r)
+		if kind == ignorable {
+			continue
+		}
+
+		if kind != letter || !unicode.IsLetter(r) {
+			return "", ErrInvalidWord
+		}
+
+		b.WriteRune([]rune(letters)[0])
+	}
+
+	n := utf8.RuneCountInString(b.String())
+	if n == 0 || n > MaxWordLength {
+		return "", ErrInvalidWord
+	}
+
+	return b.String(), nil
+}
+
+type entry struct {
+	word   []rune
+	list   string
+	action Action
+}
+
+// Filter matches text against a fixed set of lists. It is safe for
+// concurrent use.
+type Filter struct {
+	byLength map[int][]entry
+}
+
+// NewFilter builds a filter from lists. Words that do not normalize are
+// skipped.
+func NewFilter(lists []List) *Filter {
+	f := &Filter{byLength: map[int][]entry{}}
+	for _, list := range lists {
+		for _, word := range list.Words {
+			normalized, err := NormalizeWord(word)
+			if err != nil {
+				continue
+			}
+
+			runes := []rune(normalized)
+			f.byLength[len(runes)] = append(f.byLength[len(runes)], entry{word: runes, list: list.Name, action: list.Action})
+		}
+	}
+
+	return f
+}
+
+type position struct {
+	kind    runeKind
+	letters string
+	start   int
+	end     int
+}
+
+// Check returns every listed word in text, in order of appearance. A word
+// on several lists matches once per list.
+func (f *Filter) Check(text string) []Match {
+	matches := []Match{}
+	for _, token := range tokenize(text) {
+		matches = append(matches, f.matchToken(token)...)
+	}
+
+	return matches
+}
+
+func (f *Filter) matchToken(token []position) []Match {
+	// a word may also be wrapped in punctuation that could pass for letters,
+	// like "!kerfuffle!"
+	first, last := 0, len(token)-1
+	for first <= last && token[first].kind == symbol {
+		first++
+	}
+	for last >= first && token[last].kind == symbol {
+		last--
+	}
+
+	candidates := [][]position{token}
+	if first > 0 || last < len(token)-1 {
+		candidates = append(candidates, token[first:last+1])
+	}
+
+	matches := []Match{}
+	matched := map[string]bool{}
+	for _, candidate := range candidates {
+		if len(candidate) == 0 {
+			continue
+		}
+
+		for _, e := range f.byLength[len(candidate)] {
+			key := e.list + "\x00" + string(e.word)
+			if matched[key] || !matchesWord(candidate, e.word) {
+				continue
+			}
+
+			matched[key] = true
+			matches = append(matches, Match{
+				Word:   string(e.word),
+				List:   e.list,
+				Action: e.action,
+				Start:  candidate[0].start,
+				End:    candidate[len(candidate)-1].end,
+			})
+		}
+	}
+
+	return matches
+}
+
+func matchesWord(token []position, word []rune) bool {
+	for i, p := range token {
+		if !strings.ContainsRune(p.letters, word[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenize splits text into words of letters and symbols. Ignorable runes
+// are folded into the span of the rune before them.
+func tokenize(text string) [][]position {
+	tokens := [][]position{}
+	current := []position{}
+	for i, r := range text {
+		kind, letters := classify(r)
+		_, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		switch kind {
+		case separator:
+			if len(current) > 0 {
+				tokens = append(tokens, current)
+				current = []position{}
+			}
+		case ignorable:
+			if len(current) > 0 {
+				current[len(current)-1].end = end
+			}
+		default:
+			current = append(current, position{kind: kind, letters: letters, start: i, end: end})
+		}
+	}
+
+	if len(current) > 0 {
+		tokens = append(tokens, current)
+	}
+
+	return tokens
+}
+
+// Verdict is the outcome of moderating a text.
+type Verdict struct {
+	// Text has every word from a Mask list replaced with "****" and is
+	// otherwise unchanged.
+	Text     string
+	Rejected bool
+	// Flagged holds the words from Flag lists, without duplicates.
+	Flagged []string
+	Matches []Match
+}
+
+// Moderate checks text and applies the actions of the lists it matched.
+func (f *Filter) Moderate(text string) Verdict {
+	v := Verdict{Matches: f.Check(text), Flagged: []string{}}
+
+	var b strings.Builder
+	written := 0
+	for _, m := range v.Matches {
+		switch m.Action {
+		case Reject:
+			v.Rejected = true
+		case Flag:
+			if !slices.Contains(v.Flagged, m.Word) {
+				v.Flagged = append(v.Flagged, m.Word)
+			}
+		case Mask:
+			if m.Start < written {
+				// already masked for another list
+				continue
+			}
+
+			b.WriteString(text[written:m.Start])
+			b.WriteString("****")
+			written = m.End
+		}
+	}
+
+	b.WriteString(text[written:])
+	v.Text = b.String()
+	return v
+}
+
+// LoadFile reads lists from a JSON file holding an array of lists.
+func LoadFile(path string) ([]List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []List{}
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, list := range lists {
+		if !list.Action.Valid() {
+			return nil, fmt.Errorf("%s: list %q: %w", path, list.Name, ErrInvalidAction)
+		}
+	}
+
+	return lists, nil
+}
@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+)
+
+func testFilter() *Filter {
+	return NewFilter([]List{
+		{Name: "default", Action: Mask, Words: []string{"kerfuffle", "sharbert", "fornax"}},
+		{Name: "spam", Action: Reject, Words: []string{"casino"}},
+		{Name: "review", Action: Flag, Words: []string{"Café"}},
+	})
+}
+
+func TestModerateMasks(t *testing.T) {
+	f := testFilter()
+	cases := map[string]string{
+		"I had something interesting for breakfast":   "I had something interesting for breakfast",
+		"This is a kerfuffle opinion I need to share": "This is a **** opinion I need to share",
+		"What a KERFUFFLE!":                           "What a ****!",
+		"Sharbert, fornax... kerfuffle?":              "****, ****... ****?",
+		"k3rfuffl3 and $harbert":                      "**** and ****",
+		"kërfüffle and ｆｏｒｎａｘ":                        "**** and ****",
+		"kerf\u200buffle":                             "****",
+		"kerfu\u0308ffle":                             "****",
+		"!fornax!":                                    "!****!",
+		"kerfufflement is fine":                       "kerfufflement is fine",
+		"  spacing   kept  ":                          "  spacing   kept  ",
+	}
+
+	for in, want := range cases {
+		if got := f.Moderate(in).Text; got != want {
+			t.Errorf("Moderate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestModerateActions(t *testing.T) {
+	f := testFilter()
+
+	if v := f.Moderate("win big at the ca$1no"); !v.Rejected {
+		t.Error("expected leetspeak casino to be rejected")
+	}
+
+	v := f.Moderate("meet me at the cafe, the CAFÉ")
+	if v.Rejected || !slices.Equal(v.Flagged, []string{"cafe"}) {
+		t.Errorf("expected cafe to be flagged once, got %+v", v)
+	}
+
+	if v.Text != "meet me at the cafe, the CAFÉ" {
+		t.Errorf("flagged words should not be changed, got %q", v.Text)
+	}
+
+	matches := f.Check("fornax casino")
+	if len(matches) != 2 || matches[0].List != "default" || matches[1].Action != Reject {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+
+	if matches[0].Start != 0 || matches[0].End != 6 {
+		t.Errorf("unexpected span %d-%d", matches[0].Start, matches[0].End)
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	cases := map[string]string{
+		"Kerfuffle": "kerfuffle",
+		" café ":    "cafe",
+		"ＦＯＲＮＡＸ":    "fornax",
+	}
+
+	for in, want := range cases {
+		if got, err := NormalizeWord(in); err != nil || got != want {
+			t.Errorf("NormalizeWord(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "two words", "h3llo", "a$$"} {
+		if _, err := NormalizeWord(in); err == nil {
+			t.Errorf("NormalizeWord(%q) should fail", in)
+		}
+	}
+}
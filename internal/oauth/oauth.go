@@ -0,0 +1,122 @@
+// Package oauth holds the protocol rules of Chirpy's OAuth 2.0 authorization
+// server: PKCE (RFC 7636), redirect URI checks and error responses.
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+)
+
+// Error codes from RFC 6749 sections 4.1.2.1 and 5.2.
+const (
+	ErrInvalidRequest       = "invalid_request"
+	ErrInvalidClient        = "invalid_client"
+	ErrInvalidGrant         = "invalid_grant"
+	ErrInvalidScope         = "invalid_scope"
+	ErrUnsupportedGrantType = "unsupported_grant_type"
+	ErrUnsupportedResponse  = "unsupported_response_type"
+	ErrAccessDenied         = "access_denied"
+)
+
+// ChallengeMethodS256 is the only PKCE method accepted; "plain" offers no
+// protection if the authorization request is observed.
+const ChallengeMethodS256 = "S256"
+
+var ErrInvalidRedirectURI = errors.New("redirect uri must be an absolute https url, or http on a loopback address, without a fragment")
+
+// ValidCodeVerifier reports whether v is 43-128 unreserved characters.
+func ValidCodeVerifier(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+
+	for _, c := range v {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' && c != '.' && c != '_' && c != '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidCodeChallenge reports whether c looks like an S256 challenge: the
+// unpadded base64url encoding of a SHA-256 digest.
+func ValidCodeChallenge(c string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	return err == nil && len(raw) == sha256.Size
+}
+
+// S256Challenge derives the code challenge for verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE reports whether verifier matches the S256 challenge sent with
+// the authorization request.
+func VerifyPKCE(verifier, challenge string) bool {
+	if !ValidCodeVerifier(verifier) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(challenge)) == 1
+}
+
+// ValidateRedirectURI checks a redirect URI a client registers.
+func ValidateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return ErrInvalidRedirectURI
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return nil
+		}
+	}
+
+	return ErrInvalidRedirectURI
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// MatchRedirectURI reports whether uri exactly matches a registered one.
+// Prefix or pattern matching would let an attacker steer codes elsewhere.
+func MatchRedirectURI(registered []string, uri string) bool {
+	return slices.Contains(registered, uri)
+}
+
+// RedirectWith appends params to a registered redirect URI, keeping any query
+// it already has.
+func RedirectWith(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,104 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{CreatedAt: t, ID: uid}, nil
+}
+
+// RankCursor marks a position in a list ordered by (rank, created_at, id),
+// such as relevance-sorted search results.
+type RankCursor struct {
+	Rank float32
+	Cursor
+}
+
+func (c RankCursor) Encode() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "|" + c.Cursor.Encode()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeRankCursor(s string) (RankCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return RankCursor{}, ErrInvalidCursor
+	}
+
+	rank, rest, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return RankCursor{}, ErrInvalidCursor
+	}
+
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return RankCursor{}, ErrInvalidCursor
+	}
+
+	cursor, err := DecodeCursor(rest)
+	if err != nil {
+		return RankCursor{}, err
+	}
+
+	return RankCursor{Rank: float32(r), Cursor: cursor}, nil
+}
+
+// ParseLimit reads a page size from a query string value, falling back to
+// DefaultLimit when empty and capping the result at MaxLimit.
+func ParseLimit(s string) (int, error) {
+	if len(s) == 0 {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, errors.New("invalid limit")
+	}
+
+	return min(limit, MaxLimit), nil
+}
@@ -0,0 +1,68 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+	decoded, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+
+	if !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID {
+		t.Errorf("cursor mismatch: expected %v, got %v", c, decoded)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", Cursor{}.Encode()[:10]} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("cursor %q should error", s)
+		}
+	}
+}
+
+func TestRankCursorRoundTrip(t *testing.T) {
+	c := RankCursor{
+		Rank:   0.0607927,
+		Cursor: Cursor{CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC), ID: uuid.New()},
+	}
+
+	decoded, err := DecodeRankCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("failed to decode rank cursor: %v", err)
+	}
+
+	if decoded.Rank != c.Rank || !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID {
+		t.Errorf("rank cursor mismatch: expected %v, got %v", c, decoded)
+	}
+
+	if _, err := DecodeRankCursor(c.Cursor.Encode()); err == nil {
+		t.Error("plain cursor should not decode as a rank cursor")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	if limit, err := ParseLimit(""); err != nil || limit != DefaultLimit {
+		t.Errorf("empty limit should default to %d, got %d", DefaultLimit, limit)
+	}
+
+	if limit, err := ParseLimit("5"); err != nil || limit != 5 {
+		t.Errorf("expected 5, got %d", limit)
+	}
+
+	if limit, err := ParseLimit("1000"); err != nil || limit != MaxLimit {
+		t.Errorf("limit should be capped at %d, got %d", MaxLimit, limit)
+	}
+
+	for _, s := range []string{"0", "-1", "ten"} {
+		if _, err := ParseLimit(s); err == nil {
+			t.Errorf("limit %q should error", s)
+		}
+	}
+}
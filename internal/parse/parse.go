@@ -0,0 +1,38 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]{1,50})`)
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]{1,30})`)
+)
+
+// Hashtags returns the distinct #tags in s, lowercased and without the leading '#'.
+func Hashtags(s string) []string {
+	return extract(hashtagRe, s)
+}
+
+// Mentions returns the distinct @handles in s, lowercased and without the
+// leading '@'. Email addresses are not treated as mentions.
+func Mentions(s string) []string {
+	return extract(mentionRe, s)
+}
+
+func extract(re *regexp.Regexp, s string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, match := range re.FindAllStringSubmatch(s, -1) {
+		word := strings.ToLower(match[1])
+		if seen[word] {
+			continue
+		}
+
+		seen[word] = true
+		out = append(out, word)
+	}
+
+	return out
+}
@@ -0,0 +1,41 @@
+package parse
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"no tags here":                  {},
+		"#golang is fun":                {"golang"},
+		"I love #Go and #go!":           {"go"},
+		"(#chirpy), #café #2024":        {"chirpy", "café", "2024"},
+		"not a tag: abc#def or &#39;":   {},
+		"#one#two":                      {"one"},
+		"tags: #snake_case, #CamelCase": {"snake_case", "camelcase"},
+	}
+
+	for input, expected := range cases {
+		if got := Hashtags(input); !slices.Equal(got, expected) {
+			t.Errorf("Hashtags(%q): expected %v, got %v", input, expected, got)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := map[string][]string{
+		"hello world":             {},
+		"@alice hi":               {"alice"},
+		"hi @Alice and @alice.":   {"alice"},
+		"cc @bob, @carol_99":      {"bob", "carol_99"},
+		"mail me at me@chirpy.io": {},
+		"@@double":                {},
+	}
+
+	for input, expected := range cases {
+		if got := Mentions(input); !slices.Equal(got, expected) {
+			t.Errorf("Mentions(%q): expected %v, got %v", input, expected, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/syeero7/boot-chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that every
+// instance sees the same limits. Each Take locks the bucket's row, so
+// concurrent requests for the same key are counted one at a time.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	q := database.New(tx)
+	now := time.Now().UTC()
+	b, err := q.LockRateLimitBucket(ctx, database.LockRateLimitBucketParams{
+		Key:       key,
+		Tokens:    float64(limit.Requests),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, res := Take(b.Tokens, b.UpdatedAt, limit, now)
+	err = q.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{
+		Key:       key,
+		Tokens:    tokens,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return res, tx.Commit()
+}
+
+func (s *PostgresStore) Sweep(ctx context.Context, before time.Time) error {
+	return database.New(s.DB).DeleteStaleRateLimitBuckets(ctx, before.UTC())
+}
@@ -0,0 +1,108 @@
+// Package ratelimit implements token bucket rate limiting. Buckets live in a
+// Store, either in memory for a single instance or in Postgres when several
+// instances share the limits.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Per on average, and bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result describes a bucket after a request has tried to take a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is free, when the request was not
+	// allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Take refills a bucket holding tokens, last updated at updated, and takes a
+// token from it if one is available. It returns the tokens left.
+func Take(tokens float64, updated time.Time, limit Limit, now time.Time) (float64, Result) {
+	capacity := float64(limit.Requests)
+	elapsed := max(now.Sub(updated).Seconds(), 0)
+	tokens = min(capacity, tokens+elapsed*limit.rate())
+
+	res := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((capacity - tokens) / limit.rate())
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type Store interface {
+	// Take spends a token from the bucket named key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Sweep forgets buckets untouched since before. A bucket that has had
+	// time to refill behaves the same as a missing one.
+	Sweep(ctx context.Context, before time.Time) error
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]bucket{}}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(limit.Requests), updated: now}
+	}
+
+	tokens, res := Take(b.tokens, b.updated, limit, now)
+	s.buckets[key] = bucket{tokens: tokens, updated: now}
+	return res, nil
+}
+
+func (s *MemoryStore) Sweep(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updated.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
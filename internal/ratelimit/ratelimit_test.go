@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tokens := float64(limit.Requests)
+	for i := range 3 {
+		var res Result
+		tokens, res = Take(tokens, now, limit, now)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i, 2-i, res)
+		}
+	}
+
+	tokens, res := Take(tokens, now, limit, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("expected to wait 1s with a 3s reset, got %+v", res)
+	}
+
+	// a token refills every second
+	_, res = Take(tokens, now, limit, now.Add(1500*time.Millisecond))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected a refilled token, got %+v", res)
+	}
+
+	// the bucket never holds more than Requests
+	_, res = Take(0, now, limit, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("expected a full bucket, got %+v", res)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Requests: 2, Per: time.Hour}
+
+	for range 2 {
+		if res, _ := store.Take(ctx, "a", limit); !res.Allowed {
+			t.Fatal("expected requests within the limit to be allowed")
+		}
+	}
+
+	if res, _ := store.Take(ctx, "a", limit); res.Allowed || res.RetryAfter <= 0 {
+		t.Errorf("expected the third request to be limited, got %+v", res)
+	}
+
+	if res, _ := store.Take(ctx, "b", limit); !res.Allowed {
+		t.Error("buckets should be independent per key")
+	}
+
+	store.Sweep(ctx, time.Now().Add(time.Minute))
+	if res, _ := store.Take(ctx, "a", limit); !res.Allowed {
+		t.Error("a swept bucket should start full")
+	}
+}
@@ -0,0 +1,109 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from RFC 6238 appendix B.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 publishes 8 digit codes; these are their last 6 digits.
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		got, err := Code(rfcSecret, Step(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", c.unix, err)
+		}
+
+		if got != c.want {
+			t.Errorf("Code(%d): expected %s, got %s", c.unix, c.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := Validate("050471", rfcSecret, now)
+	if !ok || step != Step(now) {
+		t.Errorf("current code should validate at step %d, got %d %v", Step(now), step, ok)
+	}
+
+	if _, ok := Validate("050471", rfcSecret, now.Add(Period)); !ok {
+		t.Error("previous step should be accepted")
+	}
+
+	if _, ok := Validate("050471", rfcSecret, now.Add(3*Period)); ok {
+		t.Error("old code should be rejected")
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "000000"} {
+		if _, ok := Validate(code, rfcSecret, now); ok {
+			t.Errorf("%q should be rejected", code)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	code, err := Code(secret, Step(time.Now()))
+	if err != nil {
+		t.Fatalf("generated secret should decode: %v", err)
+	}
+
+	if _, ok := Validate(code, secret, time.Now()); !ok {
+		t.Error("code from generated secret should validate")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Chirpy", "walt@breakingbad.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:walt@breakingbad.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+
+	if !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Chirpy") {
+		t.Errorf("uri is missing parameters: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("failed to generate recovery codes: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("duplicate recovery code %s", code)
+		}
+		seen[code] = true
+
+		if len(code) != 23 || strings.Count(code, "-") != 3 {
+			t.Errorf("recovery code %s should be four groups of five characters", code)
+		}
+
+		if NormalizeRecoveryCode(strings.ToUpper(code)) != strings.ReplaceAll(code, "-", "") {
+			t.Errorf("normalizing %s should strip formatting", code)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinPasswordLength    = 8
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+)
+
+var handleRe = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// reservedHandles would collide with routes under /api/users.
+var reservedHandles = []string{"me", "admin", "root", "chirpy", "support"}
+
+func Handle(handle string) error {
+	if !handleRe.MatchString(handle) {
+		return errors.New("must be 3-30 letters, digits or underscores")
+	}
+
+	if slices.Contains(reservedHandles, strings.ToLower(handle)) {
+		return errors.New("is reserved")
+	}
+
+	return nil
+}
+
+func DisplayName(name string) error {
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return errors.New("is too long")
+	}
+
+	return nil
+}
+
+func Bio(bio string) error {
+	if utf8.RuneCountInString(bio) > MaxBioLength {
+		return errors.New("is too long")
+	}
+
+	return nil
+}
+
+func Email(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("is not a valid email address")
+	}
+
+	return nil
+}
+
+func Password(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Errorf("must be at least %d characters", MinPasswordLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	for _, handle := range []string{"bob", "Alice_99", "a_b", strings.Repeat("x", 30)} {
+		if err := Handle(handle); err != nil {
+			t.Errorf("handle %q should be valid: %v", handle, err)
+		}
+	}
+
+	for _, handle := range []string{"", "ab", "has space", "dash-ed", "émile", strings.Repeat("x", 31), "me", "Admin"} {
+		if err := Handle(handle); err == nil {
+			t.Errorf("handle %q should be invalid", handle)
+		}
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	if err := DisplayName(strings.Repeat("é", MaxDisplayNameLength)); err != nil {
+		t.Errorf("display name at the limit should be valid: %v", err)
+	}
+
+	if err := DisplayName(strings.Repeat("é", MaxDisplayNameLength+1)); err == nil {
+		t.Error("display name over the limit should be invalid")
+	}
+}
+
+func TestBio(t *testing.T) {
+	if err := Bio(""); err != nil {
+		t.Errorf("empty bio should be valid: %v", err)
+	}
+
+	if err := Bio(strings.Repeat("a", MaxBioLength+1)); err == nil {
+		t.Error("bio over the limit should be invalid")
+	}
+}
+
+func TestEmail(t *testing.T) {
+	if err := Email("walt@breakingbad.com"); err != nil {
+		t.Errorf("email should be valid: %v", err)
+	}
+
+	for _, email := range []string{"", "walt", "Walt <walt@breakingbad.com>", "walt@"} {
+		if err := Email(email); err == nil {
+			t.Errorf("email %q should be invalid", email)
+		}
+	}
+}
+
+func TestPassword(t *testing.T) {
+	if err := Password("password1"); err != nil {
+		t.Errorf("password should be valid: %v", err)
+	}
+
+	if err := Password("short"); err == nil {
+		t.Error("short password should be invalid")
+	}
+}
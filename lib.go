@@ -1,10 +1,15 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
-	"slices"
-	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/syeero7/boot-chirpy/internal/pagination"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string) {
@@ -20,6 +25,28 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	}
 }
 
+// respondWithValidationErrors reports every invalid request field at once,
+// keyed by the field's JSON name.
+func respondWithValidationErrors(w http.ResponseWriter, fields map[string]string) {
+	type errorRes struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+
+	respondWithJSON(w, http.StatusBadRequest, &errorRes{Error: "Validation failed", Fields: fields})
+}
+
+// respondWithOAuthError writes the RFC 6749 error body used by the OAuth
+// endpoints, which clients parse by its error code.
+func respondWithOAuthError(w http.ResponseWriter, code int, errCode, description string) {
+	type errorRes struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+
+	respondWithJSON(w, code, &errorRes{Error: errCode, ErrorDescription: description})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -32,15 +59,100 @@ func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	w.Write(data)
 }
 
-func filterProfanity(s string) string {
-	badWords := []string{"kerfuffle", "sharbert", "fornax"}
-	words := strings.Fields(s)
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// userConflict maps unique constraint violations on users to a client message.
+func userConflict(err error) (string, bool) {
+	switch {
+	case isUniqueViolation(err, "users_email_key"):
+		return "Email is already in use", true
+	case isUniqueViolation(err, "users_handle_idx"):
+		return "Handle is already taken", true
+	}
+
+	return "", false
+}
 
-	for i, word := range words {
-		if slices.Contains(badWords, strings.ToLower(word)) {
-			words[i] = "****"
+type pageParams struct {
+	limit  int
+	cursor *pagination.Cursor
+}
+
+type pageRes[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func parsePageParams(req *http.Request) (pageParams, error) {
+	query := req.URL.Query()
+	limit, err := pagination.ParseLimit(query.Get("limit"))
+	if err != nil {
+		return pageParams{}, err
+	}
+
+	page := pageParams{limit: limit}
+	if s := query.Get("cursor"); len(s) > 0 {
+		cursor, err := pagination.DecodeCursor(s)
+		if err != nil {
+			return pageParams{}, err
+		}
+
+		page.cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// fetchLimit asks for one extra row so paginate can tell whether a next page exists.
+func (p pageParams) fetchLimit() int32 {
+	return int32(p.limit + 1)
+}
+
+func (p pageParams) afterCreatedAt() sql.NullTime {
+	if p.cursor == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: p.cursor.CreatedAt, Valid: true}
+}
+
+func (p pageParams) afterID() uuid.NullUUID {
+	if p.cursor == nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: p.cursor.ID, Valid: true}
+}
+
+func paginate[T any](items []T, limit int, cursorOf func(T) pagination.Cursor) ([]T, string) {
+	if len(items) <= limit {
+		if items == nil {
+			items = []T{}
 		}
+
+		return items, ""
 	}
 
-	return strings.Join(words, " ")
+	items = items[:limit]
+	return items, cursorOf(items[len(items)-1]).Encode()
 }
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/syeero7/boot-chirpy/internal/auth"
 	"github.com/syeero7/boot-chirpy/internal/database"
+	"github.com/syeero7/boot-chirpy/internal/mailer"
+	"github.com/syeero7/boot-chirpy/internal/moderation"
+	"github.com/syeero7/boot-chirpy/internal/ratelimit"
 )
 
 func main() {
@@ -23,30 +31,260 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), database.New(db), os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// challenge and verification tokens are always signed with the secret,
+	// even when access tokens use JWT_KEYS_DIR
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+
+	jwtKeys, err := loadJWTKeys(jwtSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	mux := http.NewServeMux()
 	config := apiConfig{
 		db:        database.New(db),
+		conn:      db,
 		platform:  platform,
 		jwtSecret: jwtSecret,
+		jwtKeys:   jwtKeys,
 		polkaKey:  polkaKey,
+		mailer:    newMailer(),
+		limiter:   newRateLimitStore(db),
+	}
+
+	if path := os.Getenv("MODERATION_FILE"); len(path) > 0 {
+		lists, err := moderation.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		config.moderationFileLists = lists
+	}
+
+	if err := config.loadModerationFilter(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	signupLimit := rateLimit{Limit: ratelimit.Limit{Requests: 5, Per: time.Hour}}
+	loginLimit := rateLimit{Limit: ratelimit.Limit{Requests: 30, Per: time.Minute}}
+	emailLimit := rateLimit{Limit: ratelimit.Limit{Requests: 5, Per: time.Hour}}
+	postLimit := rateLimit{
+		Limit: ratelimit.Limit{Requests: 50, Per: time.Hour},
+		Red:   ratelimit.Limit{Requests: 200, Per: time.Hour},
+	}
+	reportLimit := rateLimit{Limit: ratelimit.Limit{Requests: 20, Per: time.Hour}}
+	interactionLimit := rateLimit{
+		Limit: ratelimit.Limit{Requests: 300, Per: time.Hour},
+		Red:   ratelimit.Limit{Requests: 1000, Per: time.Hour},
 	}
 
 	mux.Handle("/app/", http.StripPrefix("/app/", config.middlewareMetricsInc(http.FileServer(http.Dir(".")))))
 
 	mux.HandleFunc("GET /api/healthz", getServerReadiness)
-	mux.HandleFunc("GET /admin/metrics", config.getRequestCount)
-	mux.HandleFunc("POST /admin/reset", config.resetServer)
-	mux.HandleFunc("POST /api/users", config.createUser)
-	mux.HandleFunc("POST /api/chirps", config.createChirp)
-	mux.HandleFunc("GET /api/chirps", config.getChirps)
-	mux.HandleFunc("GET /api/chirps/{chirp_id}", config.getChirpByID)
-	mux.HandleFunc("POST /api/login", config.loginUser)
+	mux.HandleFunc("GET /.well-known/jwks.json", config.getJWKS)
+	mux.HandleFunc("GET /admin/metrics", config.requireRole(roleAdmin, config.getRequestCount))
+	mux.HandleFunc("POST /admin/reset", config.requireRole(roleAdmin, config.resetServer))
+	mux.HandleFunc("GET /admin/moderation/lists", config.requireRole(roleAdmin, config.getModerationLists))
+	mux.HandleFunc("POST /admin/moderation/lists", config.requireRole(roleAdmin, config.createModerationList))
+	mux.HandleFunc("PATCH /admin/moderation/lists/{id}", config.requireRole(roleAdmin, config.updateModerationList))
+	mux.HandleFunc("DELETE /admin/moderation/lists/{id}", config.requireRole(roleAdmin, config.deleteModerationList))
+	mux.HandleFunc("POST /admin/moderation/lists/{id}/words", config.requireRole(roleAdmin, config.addModerationWords))
+	mux.HandleFunc("DELETE /admin/moderation/lists/{id}/words/{word}", config.requireRole(roleAdmin, config.deleteModerationWord))
+	mux.HandleFunc("GET /admin/moderation/flags", config.requireRole(roleModerator, config.getFlaggedChirps))
+	mux.HandleFunc("DELETE /admin/moderation/flags/{chirp_id}", config.requireRole(roleModerator, config.dismissChirpFlag))
+	mux.HandleFunc("GET /admin/reports", config.requireRole(roleModerator, config.getReports))
+	mux.HandleFunc("PATCH /admin/reports/{id}", config.requireRole(roleModerator, config.closeReport))
+	mux.HandleFunc("POST /admin/chirps/{chirp_id}/hide", config.requireRole(roleModerator, config.hideChirp))
+	mux.HandleFunc("POST /admin/chirps/{chirp_id}/restore", config.requireRole(roleModerator, config.restoreChirp))
+	mux.HandleFunc("POST /admin/users/{id}/suspend", config.requireRole(roleModerator, config.suspendUser))
+	mux.HandleFunc("POST /admin/users/{id}/unsuspend", config.requireRole(roleModerator, config.unsuspendUser))
+	mux.HandleFunc("PUT /admin/users/{id}/role", config.requireRole(roleAdmin, config.setUserRole))
+	mux.HandleFunc("POST /api/users", config.rateLimited(signupLimit, config.createUser))
+	mux.HandleFunc("POST /api/chirps", config.requireAuth(auth.ScopeChirpsWrite, config.rateLimited(postLimit, config.createChirp)))
+	mux.HandleFunc("GET /api/chirps", config.optionalAuth(config.getChirps))
+	mux.HandleFunc("GET /api/chirps/search", config.optionalAuth(config.searchChirps))
+	mux.HandleFunc("GET /api/chirps/{chirp_id}", config.optionalAuth(config.getChirpByID))
+	mux.HandleFunc("POST /api/login", config.rateLimited(loginLimit, config.loginUser))
+	mux.HandleFunc("POST /api/login/2fa", config.rateLimited(loginLimit, config.loginTwoFactor))
+	mux.HandleFunc("POST /api/password/forgot", config.rateLimited(emailLimit, config.forgotPassword))
+	mux.HandleFunc("POST /api/password/reset", config.resetPassword)
 	mux.HandleFunc("POST /api/refresh", config.createRefreshToken)
 	mux.HandleFunc("POST /api/revoke", config.revokeRefreshToken)
-	mux.HandleFunc("PUT /api/users", config.updateUserData)
-	mux.HandleFunc("DELETE /api/chirps/{chirp_id}", config.deleteChirp)
+	mux.HandleFunc("POST /api/users/verify", config.verifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", config.requireAuth(auth.ScopeProfileWrite, config.rateLimited(emailLimit, config.resendVerificationEmail)))
+	mux.HandleFunc("PUT /api/users", config.requireAuth(auth.ScopeAccount, config.replaceUserData))
+	mux.HandleFunc("PATCH /api/users/me", config.requireAuth(auth.ScopeProfileWrite, config.updateUserData))
+	mux.HandleFunc("GET /api/users/me", config.requireAuth(auth.ScopeProfileRead, config.getCurrentUser))
+	mux.HandleFunc("DELETE /api/users/me", config.requireAuth(auth.ScopeAccount, config.deleteCurrentUser))
+	mux.HandleFunc("GET /api/users/me/export", config.requireAuth(auth.ScopeAccount, config.exportUserData))
+	mux.HandleFunc("POST /api/users/me/2fa", config.requireAuth(auth.ScopeAccount, config.enrollTwoFactor))
+	mux.HandleFunc("POST /api/users/me/2fa/confirm", config.requireAuth(auth.ScopeAccount, config.confirmTwoFactor))
+	mux.HandleFunc("POST /api/users/me/2fa/recovery-codes", config.requireAuth(auth.ScopeAccount, config.regenerateRecoveryCodes))
+	mux.HandleFunc("DELETE /api/users/me/2fa", config.requireAuth(auth.ScopeAccount, config.disableTwoFactor))
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}", config.requireAuth(auth.ScopeChirpsWrite, config.deleteChirp))
+	mux.HandleFunc("GET /api/sessions", config.requireAuth(auth.ScopeAccount, config.getSessions))
+	mux.HandleFunc("DELETE /api/sessions/{id}", config.requireAuth(auth.ScopeAccount, config.revokeSession))
+	mux.HandleFunc("POST /api/sessions/revoke-all", config.requireAuth(auth.ScopeAccount, config.revokeAllSessions))
+	mux.HandleFunc("POST /api/tokens", config.requireAuth(auth.ScopeAccount, config.createPersonalAccessToken))
+	mux.HandleFunc("GET /api/tokens", config.requireAuth(auth.ScopeAccount, config.getPersonalAccessTokens))
+	mux.HandleFunc("DELETE /api/tokens/{id}", config.requireAuth(auth.ScopeAccount, config.revokePersonalAccessToken))
+	mux.HandleFunc("POST /api/oauth/clients", config.requireAuth(auth.ScopeAccount, config.createOAuthClient))
+	mux.HandleFunc("GET /api/oauth/clients", config.requireAuth(auth.ScopeAccount, config.getOAuthClients))
+	mux.HandleFunc("DELETE /api/oauth/clients/{id}", config.requireAuth(auth.ScopeAccount, config.deleteOAuthClient))
+	mux.HandleFunc("GET /api/oauth/authorize", config.requireAuth(auth.ScopeAccount, config.getAuthorization))
+	mux.HandleFunc("POST /api/oauth/authorize", config.requireAuth(auth.ScopeAccount, config.approveAuthorization))
+	mux.HandleFunc("POST /api/oauth/token", config.rateLimited(loginLimit, config.oauthToken))
+	mux.HandleFunc("GET /api/oauth/consents", config.requireAuth(auth.ScopeAccount, config.getOAuthConsents))
+	mux.HandleFunc("DELETE /api/oauth/consents/{client_id}", config.requireAuth(auth.ScopeAccount, config.revokeOAuthConsent))
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/replies", config.optionalAuth(config.getReplies))
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/thread", config.optionalAuth(config.getThread))
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/rechirp", config.requireAuth(auth.ScopeChirpsWrite, config.rateLimited(postLimit, config.rechirp)))
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/rechirp", config.requireAuth(auth.ScopeChirpsWrite, config.undoRechirp))
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/like", config.requireAuth(auth.ScopeChirpsWrite, config.rateLimited(interactionLimit, config.likeChirp)))
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/report", config.requireAuth(auth.ScopeChirpsWrite, config.rateLimited(reportLimit, config.reportChirp)))
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/like", config.requireAuth(auth.ScopeChirpsWrite, config.unlikeChirp))
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", config.optionalAuth(config.getChirpsByTag))
+	mux.HandleFunc("GET /api/trending", config.getTrendingTags)
+	mux.HandleFunc("GET /api/users/{handle}", config.getUserProfile)
+	mux.HandleFunc("POST /api/users/{id}/follow", config.requireAuth(auth.ScopeFollowsWrite, config.rateLimited(interactionLimit, config.followUser)))
+	mux.HandleFunc("DELETE /api/users/{id}/follow", config.requireAuth(auth.ScopeFollowsWrite, config.unfollowUser))
+	mux.HandleFunc("POST /api/users/{id}/block", config.requireAuth(auth.ScopeFollowsWrite, config.rateLimited(interactionLimit, config.blockUser)))
+	mux.HandleFunc("DELETE /api/users/{id}/block", config.requireAuth(auth.ScopeFollowsWrite, config.unblockUser))
+	mux.HandleFunc("GET /api/users/me/blocks", config.requireAuth(auth.ScopeProfileRead, config.getBlockedUsers))
+	mux.HandleFunc("POST /api/users/{id}/mute", config.requireAuth(auth.ScopeFollowsWrite, config.rateLimited(interactionLimit, config.muteUser)))
+	mux.HandleFunc("DELETE /api/users/{id}/mute", config.requireAuth(auth.ScopeFollowsWrite, config.unmuteUser))
+	mux.HandleFunc("GET /api/users/me/mutes", config.requireAuth(auth.ScopeProfileRead, config.getMutedUsers))
+	mux.HandleFunc("GET /api/users/{id}/followers", config.getFollowers)
+	mux.HandleFunc("GET /api/users/{id}/following", config.getFollowing)
+	mux.HandleFunc("GET /api/timeline", config.requireAuth(auth.ScopeTimelineRead, config.getTimeline))
 	mux.HandleFunc("POST /api/polka/webhooks", config.upgradeChirpyMembership)
 
+	go config.purgeDeletedUsers(1 * time.Hour)
+	go config.purgeLoginThrottles(1 * time.Hour)
+	go config.sweepRateLimits(10 * time.Minute)
+	go config.refreshModerationFilter(1 * time.Minute)
+
 	server := &http.Server{Addr: ":8080", Handler: mux}
 	log.Fatal(server.ListenAndServe())
 }
+
+// newMailer sends through SMTP when SMTP_ADDR is set, otherwise it writes
+// messages to MAIL_DIR or, failing that, to the server log.
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if len(from) == 0 {
+		from = "noreply@chirpy.local"
+	}
+
+	if addr := os.Getenv("SMTP_ADDR"); len(addr) > 0 {
+		var smtpAuth smtp.Auth
+		if username := os.Getenv("SMTP_USERNAME"); len(username) > 0 {
+			host, _, _ := net.SplitHostPort(addr)
+			smtpAuth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+
+		return &mailer.SMTPMailer{Addr: addr, From: from, Auth: smtpAuth}
+	}
+
+	if dir := os.Getenv("MAIL_DIR"); len(dir) > 0 {
+		return &mailer.FileMailer{Dir: dir, From: from}
+	}
+
+	return &mailer.LogMailer{Logger: log.Default()}
+}
+
+// loadJWTKeys reads the access token signing keys from JWT_KEYS_DIR, signing
+// with JWT_ACTIVE_KID. Without a key directory tokens fall back to HS256 with
+// JWT_SECRET, which other services cannot verify.
+func loadJWTKeys(jwtSecret string) (*auth.KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir != "" {
+		return auth.LoadKeySet(dir, os.Getenv("JWT_ACTIVE_KID"))
+	}
+
+	return auth.NewKeySet("default", auth.NewHMACKey("default", []byte(jwtSecret)))
+}
+
+// newRateLimitStore shares rate limits between instances through Postgres
+// when RATE_LIMIT_STORE=postgres. Otherwise each instance counts requests in
+// memory.
+func newRateLimitStore(db *sql.DB) ratelimit.Store {
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		return &ratelimit.PostgresStore{DB: db}
+	}
+
+	return ratelimit.NewMemoryStore()
+}
+
+// purgeDeletedUsers hard deletes accounts whose grace period has ended. The
+// ON DELETE CASCADE foreign keys remove everything the accounts owned.
+func (cfg *apiConfig) purgeDeletedUsers(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().UTC().Add(-accountDeletionGracePeriod)
+		purged, err := cfg.db.PurgeDeletedUsers(context.Background(), before)
+		if err != nil {
+			log.Printf("failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted users", purged)
+		}
+
+		<-ticker.C
+	}
+}
+
+// purgeLoginThrottles removes failed login counters that are too old to count
+// any more, so addresses that only failed once don't pile up.
+func (cfg *apiConfig) purgeLoginThrottles(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().UTC().Add(-loginFailureWindow)
+		if _, err := cfg.db.DeleteStaleLoginThrottles(context.Background(), before); err != nil {
+			log.Printf("failed to purge login throttles: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+// sweepRateLimits forgets rate limit buckets that have been idle for a day.
+// No route's limit takes that long to refill, so they would be full anyway.
+func (cfg *apiConfig) sweepRateLimits(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().Add(-24 * time.Hour)
+		if err := cfg.limiter.Sweep(context.Background(), before); err != nil {
+			log.Printf("failed to sweep rate limits: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+// refreshModerationFilter picks up list changes made through other
+// instances. Changes made through this one apply straight away.
+func (cfg *apiConfig) refreshModerationFilter(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := cfg.loadModerationFilter(context.Background()); err != nil {
+			log.Printf("failed to refresh moderation lists: %v", err)
+		}
+	}
+}
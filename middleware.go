@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/syeero7/boot-chirpy/internal/auth"
+	"github.com/syeero7/boot-chirpy/internal/database"
+	"github.com/syeero7/boot-chirpy/internal/ratelimit"
+)
+
+// errUnauthenticated marks bearer tokens that are missing, malformed, expired
+// or revoked, as opposed to a failure looking them up.
+var errUnauthenticated = errors.New("unauthenticated")
+
+// errSuspended marks a valid token whose account is suspended.
+var errSuspended = errors.New("account suspended")
+
+// requestAuth is what the auth middleware learns about the caller. It is
+// loaded once per request and read by handlers with requestUser and
+// requestViewer.
+type requestAuth struct {
+	User   database.User
+	Scopes []string
+}
+
+type requestAuthKey struct{}
+
+// requireAuth rejects requests without a valid bearer token granting scope
+// and makes the caller available to next through requestUser.
+func (cfg *apiConfig) requireAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ra, err := cfg.authenticate(req)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+
+		if !auth.HasScope(ra.Scopes, scope) {
+			respondWithAuthError(w, auth.ErrInsufficientScope)
+			return
+		}
+
+		next(w, req.WithContext(context.WithValue(req.Context(), requestAuthKey{}, ra)))
+	}
+}
+
+// optionalAuth identifies the caller on public routes. A missing or invalid
+// bearer token, or a suspended account, is treated as an anonymous request.
+func (cfg *apiConfig) optionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ra, err := cfg.authenticate(req)
+		if err != nil {
+			if !errors.Is(err, errUnauthenticated) && !errors.Is(err, errSuspended) {
+				respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+				return
+			}
+
+			next(w, req)
+			return
+		}
+
+		next(w, req.WithContext(context.WithValue(req.Context(), requestAuthKey{}, ra)))
+	}
+}
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// roleRanks orders the roles; each role can do everything the ones below it
+// can.
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+func hasRole(user database.User, role string) bool {
+	return roleRanks[user.Role] >= roleRanks[role]
+}
+
+// requireRole guards the /admin routes. The caller must be logged in with at
+// least role; personal access tokens and OAuth tokens lack the account scope
+// and are refused.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.requireAuth(auth.ScopeAccount, func(w http.ResponseWriter, req *http.Request) {
+		if !hasRole(requestUser(req), role) {
+			respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			return
+		}
+
+		next(w, req)
+	})
+}
+
+// rateLimit is a route's rate limit. Chirpy Red members get Red instead, when
+// it is set.
+type rateLimit struct {
+	Limit ratelimit.Limit
+	Red   ratelimit.Limit
+}
+
+// rateLimited spends a token from the caller's bucket for the route before
+// calling next. Authenticated callers are limited per account and anyone else
+// per IP, so it goes inside requireAuth or optionalAuth on routes that have
+// them.
+func (cfg *apiConfig) rateLimited(policy rateLimit, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		limit := policy.Limit
+		key := "ip:" + clientIP(req)
+		if ra, ok := req.Context().Value(requestAuthKey{}).(requestAuth); ok {
+			key = "user:" + ra.User.ID.String()
+			if ra.User.IsChirpyRed && policy.Red.Requests > 0 {
+				limit = policy.Red
+			}
+		}
+
+		res, err := cfg.limiter.Take(req.Context(), req.Pattern+" "+key, limit)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", fmt.Sprint(res.Limit))
+		w.Header().Set("RateLimit-Remaining", fmt.Sprint(res.Remaining))
+		w.Header().Set("RateLimit-Reset", fmt.Sprint(math.Ceil(res.Reset.Seconds())))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", fmt.Sprint(math.Ceil(res.RetryAfter.Seconds())))
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests, try again later")
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// authenticate resolves the caller from the bearer token, which may be an
+// access token or a personal access token, and loads their account.
+func (cfg *apiConfig) authenticate(req *http.Request) (requestAuth, error) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return requestAuth{}, errUnauthenticated
+	}
+
+	var userID uuid.UUID
+	var scopes []string
+	if strings.HasPrefix(token, auth.PersonalAccessTokenPrefix) {
+		// expiries are set from Go, so they are checked against Go's clock
+		now := time.Now().UTC()
+		pat, err := cfg.db.GetPersonalAccessTokenByHash(req.Context(), database.GetPersonalAccessTokenByHashParams{
+			TokenHash: auth.HashToken(token),
+			Now:       now,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return requestAuth{}, errUnauthenticated
+		}
+
+		if err != nil {
+			return requestAuth{}, err
+		}
+
+		if err := cfg.db.TouchPersonalAccessToken(req.Context(), database.TouchPersonalAccessTokenParams{Now: now, ID: pat.ID}); err != nil {
+			return requestAuth{}, err
+		}
+
+		userID, scopes = pat.UserID, pat.Scopes
+	} else {
+		userID, scopes, err = auth.ValidateJWT(token, cfg.jwtKeys)
+		if err != nil {
+			return requestAuth{}, errUnauthenticated
+		}
+	}
+
+	// a deleted account's access tokens stop working straight away
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return requestAuth{}, errUnauthenticated
+	}
+
+	if err != nil {
+		return requestAuth{}, err
+	}
+
+	if userSuspended(user) {
+		return requestAuth{}, errSuspended
+	}
+
+	return requestAuth{User: user, Scopes: scopes}, nil
+}
+
+// userSuspended reports whether user is serving a suspension. Suspensions
+// with an end date lapse on their own.
+func userSuspended(user database.User) bool {
+	return user.SuspendedAt.Valid && (!user.SuspendedUntil.Valid || user.SuspendedUntil.Time.After(time.Now().UTC()))
+}
+
+// respondWithAuthError reports a failed authentication. A valid token lacking
+// the route's scope, or belonging to a suspended account, is forbidden rather
+// than unauthorized, and only lookup failures are server errors.
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInsufficientScope):
+		respondWithError(w, http.StatusForbidden, "Token does not grant the required scope")
+	case errors.Is(err, errSuspended):
+		respondWithError(w, http.StatusForbidden, "Account suspended")
+	case errors.Is(err, errUnauthenticated):
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
+
+// requestUser returns the caller on routes wrapped in requireAuth.
+func requestUser(req *http.Request) database.User {
+	ra, _ := req.Context().Value(requestAuthKey{}).(requestAuth)
+	return ra.User
+}
+
+// requestViewer returns the caller on routes wrapped in optionalAuth, or null
+// for anonymous requests.
+func requestViewer(req *http.Request) uuid.NullUUID {
+	ra, ok := req.Context().Value(requestAuthKey{}).(requestAuth)
+	if !ok {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: ra.User.ID, Valid: true}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/syeero7/boot-chirpy/internal/database"
+	"github.com/syeero7/boot-chirpy/internal/moderation"
+)
+
+type userRes struct {
+	ID               uuid.UUID `json:"id"`
+	Email            string    `json:"email"`
+	EmailVerified    bool      `json:"email_verified"`
+	Handle           *string   `json:"handle"`
+	DisplayName      string    `json:"display_name"`
+	Bio              string    `json:"bio"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	IsChirpyRed      bool      `json:"is_chirpy_red"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	Role             string    `json:"role"`
+}
+
+// newUserRes is the private view of a user, returned only to the user
+// themselves. It never includes the password hash.
+func newUserRes(user database.User) userRes {
+	res := userRes{
+		ID:               user.ID,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		DisplayName:      user.DisplayName,
+		Bio:              user.Bio,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		IsChirpyRed:      user.IsChirpyRed,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+		Role:             user.Role,
+	}
+
+	if user.Handle.Valid {
+		res.Handle = &user.Handle.String
+	}
+
+	return res
+}
+
+type userRoleRes struct {
+	ID   uuid.UUID `json:"id"`
+	Role string    `json:"role"`
+}
+
+type personalAccessTokenRes struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// newPersonalAccessTokenRes describes a token without its secret, which is
+// only ever returned when the token is created.
+func newPersonalAccessTokenRes(pat database.PersonalAccessToken) personalAccessTokenRes {
+	res := personalAccessTokenRes{
+		ID:        pat.ID,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+		CreatedAt: pat.CreatedAt,
+	}
+
+	if pat.LastUsedAt.Valid {
+		res.LastUsedAt = &pat.LastUsedAt.Time
+	}
+
+	if pat.ExpiresAt.Valid {
+		res.ExpiresAt = &pat.ExpiresAt.Time
+	}
+
+	return res
+}
+
+type oauthClientRes struct {
+	ClientID     uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientRes(client database.OauthClient) oauthClientRes {
+	return oauthClientRes{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+type moderationListRes struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"name"`
+	Action    moderation.Action `json:"action"`
+	Words     []string          `json:"words"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func newModerationListRes(list database.ModerationList, words []string) moderationListRes {
+	if words == nil {
+		words = []string{}
+	}
+
+	return moderationListRes{
+		ID:        list.ID,
+		Name:      list.Name,
+		Action:    moderation.Action(list.Action),
+		Words:     words,
+		CreatedAt: list.CreatedAt,
+		UpdatedAt: list.UpdatedAt,
+	}
+}
+
+type reportRes struct {
+	ID         uuid.UUID  `json:"id"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ClosedAt   *time.Time `json:"closed_at"`
+}
+
+func newReportRes(report database.Report) reportRes {
+	res := reportRes{
+		ID:         report.ID,
+		ChirpID:    report.ChirpID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		CreatedAt:  report.CreatedAt,
+	}
+
+	if report.ClosedAt.Valid {
+		res.ClosedAt = &report.ClosedAt.Time
+	}
+
+	return res
+}
+
+type queuedReportRes struct {
+	reportRes
+	Chirp chirpRes `json:"chirp"`
+}
+
+type flaggedChirpRes struct {
+	Chirp     chirpRes  `json:"chirp"`
+	Words     []string  `json:"words"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+type chirpRes struct {
+	ID            uuid.UUID     `json:"id"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	ReplyToID     uuid.NullUUID `json:"reply_to_id"`
+	RechirpOfID   uuid.NullUUID `json:"rechirp_of_id"`
+	QuotedChirpID uuid.NullUUID `json:"quoted_chirp_id"`
+	LikeCount     int64         `json:"like_count"`
+	LikedByMe     bool          `json:"liked_by_me"`
+	Hidden        bool          `json:"hidden"`
+	Mentions      []uuid.UUID   `json:"mentions"`
+	RechirpOf     *chirpRes     `json:"rechirp_of,omitempty"`
+	QuotedChirp   *chirpRes     `json:"quoted_chirp,omitempty"`
+}
+
+// chirpResponses decorates chirps with their like stats and mentioned users
+// and embeds the chirps they rechirp or quote, using a fixed number of
+// queries for the whole batch. viewerID may be null for anonymous requests.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, viewerID uuid.NullUUID, chirps []database.Chirp) ([]chirpRes, error) {
+	res := make([]chirpRes, 0, len(chirps))
+	if len(chirps) == 0 {
+		return res, nil
+	}
+
+	refIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOfID.Valid {
+			refIDs = append(refIDs, chirp.RechirpOfID.UUID)
+		}
+
+		if chirp.QuotedChirpID.Valid {
+			refIDs = append(refIDs, chirp.QuotedChirpID.UUID)
+		}
+	}
+
+	refs := []database.Chirp{}
+	if len(refIDs) > 0 {
+		tmp, err := cfg.db.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{Ids: refIDs, ViewerID: viewerID})
+		if err != nil {
+			return nil, err
+		}
+
+		refs = tmp
+	}
+
+	all := append(append([]database.Chirp{}, chirps...), refs...)
+	ids := make([]uuid.UUID, 0, len(all))
+	for _, chirp := range all {
+		ids = append(ids, chirp.ID)
+	}
+
+	stats, err := cfg.db.GetChirpLikeStats(ctx, database.GetChirpLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statsByID := make(map[uuid.UUID]database.GetChirpLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		statsByID[stat.ChirpID] = stat
+	}
+
+	mentions, err := cfg.db.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mentionsByID := make(map[uuid.UUID][]uuid.UUID, len(mentions))
+	for _, mention := range mentions {
+		mentionsByID[mention.ChirpID] = append(mentionsByID[mention.ChirpID], mention.UserID)
+	}
+
+	toRes := func(chirp database.Chirp) chirpRes {
+		stat := statsByID[chirp.ID]
+		chirpMentions := mentionsByID[chirp.ID]
+		if chirpMentions == nil {
+			chirpMentions = []uuid.UUID{}
+		}
+
+		return chirpRes{
+			ID:            chirp.ID,
+			Body:          chirp.Body,
+			UserID:        chirp.UserID,
+			CreatedAt:     chirp.CreatedAt,
+			UpdatedAt:     chirp.UpdatedAt,
+			ReplyToID:     chirp.ReplyToID,
+			RechirpOfID:   chirp.RechirpOfID,
+			QuotedChirpID: chirp.QuotedChirpID,
+			LikeCount:     stat.LikeCount,
+			LikedByMe:     stat.LikedByMe,
+			Hidden:        chirp.HiddenAt.Valid,
+			Mentions:      chirpMentions,
+		}
+	}
+
+	refsByID := make(map[uuid.UUID]chirpRes, len(refs))
+	for _, ref := range refs {
+		refsByID[ref.ID] = toRes(ref)
+	}
+
+	for _, chirp := range chirps {
+		r := toRes(chirp)
+		if ref, ok := refsByID[chirp.RechirpOfID.UUID]; ok && chirp.RechirpOfID.Valid {
+			r.RechirpOf = &ref
+		}
+
+		if ref, ok := refsByID[chirp.QuotedChirpID.UUID]; ok && chirp.QuotedChirpID.Valid {
+			r.QuotedChirp = &ref
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, viewerID uuid.NullUUID, chirp database.Chirp) (chirpRes, error) {
+	res, err := cfg.chirpResponses(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return chirpRes{}, err
+	}
+
+	return res[0], nil
+}
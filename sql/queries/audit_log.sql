@@ -0,0 +1,3 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (user_id, action, ip_address, detail)
+VALUES ($1, $2, $3, $4);
@@ -0,0 +1,24 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlockedBetween :one
+-- Blocks work both ways, whichever user made them.
+SELECT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocker_id = sqlc.arg('user_id') AND blocked_id = sqlc.arg('other_id'))
+  OR (blocker_id = sqlc.arg('other_id') AND blocked_id = sqlc.arg('user_id'))
+);
+
+-- name: GetBlockedUsers :many
+SELECT blocked_id AS user_id, created_at FROM blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, blocked_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,14 @@
+-- name: CreateChirpMentions :exec
+-- Users who blocked the author can't be mentioned by them.
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg('chirp_id')::uuid, id FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg('handles')::text[])
+AND NOT EXISTS (
+  SELECT 1 FROM blocks b INNER JOIN chirps c ON c.user_id = b.blocked_id
+  WHERE c.id = sqlc.arg('chirp_id')::uuid AND b.blocker_id = users.id
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetChirpMentions :many
+SELECT chirp_id, user_id FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
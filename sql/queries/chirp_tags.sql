@@ -0,0 +1,39 @@
+-- name: CreateChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('tags')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: GetChirpsByTag :many
+SELECT c.* FROM chirps c
+INNER JOIN chirp_tags t ON t.chirp_id = c.id
+WHERE t.tag = sqlc.arg('tag')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.deleted_at IS NOT NULL)
+AND (c.hidden_at IS NULL OR c.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = c.user_id)
+  OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.narg('viewer_id')
+  AND (m.muted_id = c.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = c.rechirp_of_id)))
+AND (c.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = c.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = sqlc.narg('viewer_id')
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+))
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (c.created_at, c.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTrendingTags :many
+SELECT tag, COUNT(*) AS uses FROM chirp_tags
+WHERE created_at > sqlc.arg('since')
+AND NOT EXISTS (SELECT 1 FROM chirps c WHERE c.id = chirp_tags.chirp_id AND c.hidden_at IS NOT NULL)
+GROUP BY tag
+ORDER BY uses DESC, tag
+LIMIT sqlc.arg('limit');
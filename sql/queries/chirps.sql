@@ -1,16 +1,176 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (body, user_id)
-VALUES ($1, $2) RETURNING *;
+INSERT INTO chirps (body, user_id, reply_to_id, quoted_chirp_id)
+VALUES ($1, $2, $3, $4) RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at;
+-- Rechirps are left out when the viewer can't see the rechirped chirp, so
+-- no empty rechirp rows point at hidden or blocked chirps.
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+AND (sqlc.narg('author_id')::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.narg('viewer_id')
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = sqlc.narg('viewer_id')
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+))
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpsDesc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+AND (sqlc.narg('author_id')::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.narg('viewer_id')
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = sqlc.narg('viewer_id')
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+))
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps WHERE id = $1;
+-- Hidden chirps are only found by their author and by moderators, and
+-- chirps are never found across a block.
+SELECT * FROM chirps
+WHERE id = sqlc.arg('id')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')));
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
 
--- name: GetChirpsByAuthorID :many
+-- name: GetReplies :many
+SELECT * FROM chirps
+WHERE reply_to_id = sqlc.arg('chirp_id')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT p.id, p.reply_to_id, 1 AS depth FROM chirps p
+  WHERE p.id = (SELECT r.reply_to_id FROM chirps r WHERE r.id = sqlc.arg('chirp_id'))
+  UNION ALL
+  SELECT p.id, p.reply_to_id, a.depth + 1 FROM chirps p
+  INNER JOIN ancestors a ON p.id = a.reply_to_id
+)
+SELECT c.* FROM chirps c
+INNER JOIN ancestors a ON a.id = c.id
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.deleted_at IS NOT NULL)
+AND (c.hidden_at IS NULL OR c.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = c.user_id)
+  OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+ORDER BY a.depth DESC;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')));
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (body, user_id, rechirp_of_id)
+VALUES ('', $1, $2)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
+SELECT * FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2;
+
+-- name: DeleteRechirp :execrows
+-- chirp_id may be a rechirp, which stands for its original like it does when
+-- rechirping. It isn't checked for visibility, so a rechirp can still be
+-- undone after its original is hidden or its author blocks the user.
+DELETE FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+AND rechirp_of_id = COALESCE(
+  (SELECT r.rechirp_of_id FROM chirps r WHERE r.id = sqlc.arg('chirp_id')),
+  sqlc.arg('chirp_id')::uuid
+);
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps),
+CAST(ts_rank(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query'))) AS REAL) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = chirps.user_id AND u.deleted_at IS NOT NULL)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')
+  OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = chirps.user_id)
+  OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+AND (sqlc.narg('author_id')::uuid IS NOT NULL
+  OR NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.narg('viewer_id')
+    AND (m.muted_id = chirps.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = chirps.rechirp_of_id))))
+AND (chirps.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = chirps.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND (o.hidden_at IS NULL OR o.user_id = sqlc.narg('viewer_id')
+    OR EXISTS (SELECT 1 FROM users v WHERE v.id = sqlc.narg('viewer_id') AND v.role IN ('moderator', 'admin')))
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = sqlc.narg('viewer_id')))
+))
+AND (sqlc.narg('after_rank')::real IS NULL
+  OR (CAST(ts_rank(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query'))) AS REAL), chirps.created_at, chirps.id)
+  < (sqlc.narg('after_rank')::real, sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpsForExport :many
 SELECT * FROM chirps WHERE user_id = $1
-ORDER BY created_at;
+ORDER BY created_at, id;
+
+-- name: SetChirpHidden :one
+UPDATE chirps
+SET hidden_at = CASE WHEN sqlc.arg('hidden')::boolean THEN COALESCE(hidden_at, NOW()) END
+WHERE id = sqlc.arg('id')
+RETURNING *;
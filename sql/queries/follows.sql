@@ -0,0 +1,55 @@
+-- name: FollowUser :execrows
+-- Nothing is inserted when either user has blocked the other.
+INSERT INTO follows (follower_id, followee_id)
+SELECT sqlc.arg('follower_id')::uuid, sqlc.arg('followee_id')::uuid
+WHERE NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.arg('follower_id') AND b.blocked_id = sqlc.arg('followee_id'))
+  OR (b.blocker_id = sqlc.arg('followee_id') AND b.blocked_id = sqlc.arg('follower_id')))
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowers :many
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = follows.follower_id AND u.deleted_at IS NOT NULL)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, follower_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetFollowing :many
+SELECT followee_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = follows.followee_id AND u.deleted_at IS NOT NULL)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, followee_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTimeline :many
+SELECT c.* FROM chirps c
+INNER JOIN follows f ON f.followee_id = c.user_id
+WHERE f.follower_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.deleted_at IS NOT NULL)
+AND c.hidden_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM blocks b
+  WHERE (b.blocker_id = sqlc.arg('user_id') AND b.blocked_id = c.user_id)
+  OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.arg('user_id')))
+AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.arg('user_id')
+  AND (m.muted_id = c.user_id OR m.muted_id = (SELECT o.user_id FROM chirps o WHERE o.id = c.rechirp_of_id)))
+AND (c.rechirp_of_id IS NULL OR EXISTS (
+  SELECT 1 FROM chirps o
+  WHERE o.id = c.rechirp_of_id
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.user_id AND u.deleted_at IS NOT NULL)
+  AND o.hidden_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('user_id') AND b.blocked_id = o.user_id)
+    OR (b.blocker_id = o.user_id AND b.blocked_id = sqlc.arg('user_id')))
+))
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (c.created_at, c.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('limit');
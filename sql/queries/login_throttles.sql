@@ -0,0 +1,31 @@
+-- name: LockLoginThrottle :one
+-- Creates the counter if it is missing and locks its row until the end of
+-- the transaction, so attempts against the same key are counted one at a
+-- time.
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES ($1, 0, $2)
+ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+RETURNING *;
+
+-- name: SetLoginFailures :exec
+UPDATE login_throttles
+SET failures = $2, last_failure_at = $3
+WHERE key = $1;
+
+-- name: ReleaseLoginAttempt :exec
+-- Takes back an attempt that was counted at reserved_at before its password
+-- or code turned out to be right. last_failure_at goes back to what it was,
+-- unless another failure has been counted since.
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0),
+  last_failure_at = CASE
+    WHEN last_failure_at = sqlc.arg('reserved_at') THEN sqlc.arg('previous_failure_at')::timestamp
+    ELSE last_failure_at
+  END
+WHERE key = sqlc.arg('key');
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_throttles WHERE key = $1;
+
+-- name: DeleteStaleLoginThrottles :execrows
+DELETE FROM login_throttles WHERE last_failure_at < $1;
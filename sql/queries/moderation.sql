@@ -0,0 +1,59 @@
+-- name: CreateModerationList :one
+INSERT INTO moderation_lists (name, action)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetModerationLists :many
+SELECT * FROM moderation_lists
+ORDER BY name;
+
+-- name: GetModerationList :one
+SELECT * FROM moderation_lists
+WHERE id = $1;
+
+-- name: UpdateModerationList :one
+UPDATE moderation_lists
+SET name = COALESCE(sqlc.narg('name'), name),
+  action = COALESCE(sqlc.narg('action'), action),
+  updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: DeleteModerationList :execrows
+DELETE FROM moderation_lists
+WHERE id = $1;
+
+-- name: GetModerationWords :many
+SELECT list_id, word FROM moderation_words
+ORDER BY word;
+
+-- name: AddModerationWords :exec
+INSERT INTO moderation_words (list_id, word)
+SELECT sqlc.arg('list_id')::uuid, unnest(sqlc.arg('words')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words
+WHERE list_id = $1 AND word = $2;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (chirp_id, words)
+VALUES ($1, $2);
+
+-- name: GetFlaggedChirps :many
+SELECT sqlc.embed(chirps), chirp_flags.words, chirp_flags.created_at AS flagged_at
+FROM chirp_flags
+INNER JOIN chirps ON chirps.id = chirp_flags.chirp_id
+WHERE sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteChirpFlag :execrows
+DELETE FROM chirp_flags
+WHERE chirp_id = $1;
+
+-- name: GetModerationListWords :many
+SELECT word FROM moderation_words
+WHERE list_id = $1
+ORDER BY word;
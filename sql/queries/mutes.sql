@@ -0,0 +1,16 @@
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT muted_id AS user_id, created_at FROM mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (created_at, muted_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,48 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (owner_id, name, redirect_uris, secret_hash)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients WHERE id = $1;
+
+-- name: GetOAuthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ConsumeOAuthAuthorizationCode :one
+-- now comes from Go, like expires_at, so both use the same clock.
+UPDATE oauth_authorization_codes
+SET used_at = sqlc.arg('now')
+WHERE code_hash = sqlc.arg('code_hash') AND used_at IS NULL AND expires_at > sqlc.arg('now')
+RETURNING *;
+
+-- name: UpsertOAuthConsent :exec
+INSERT INTO oauth_consents (user_id, client_id, scopes)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = EXCLUDED.scopes, updated_at = NOW();
+
+-- name: GetOAuthConsent :one
+SELECT * FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: GetOAuthConsentsByUser :many
+SELECT c.client_id, cl.name AS client_name, c.scopes, c.created_at, c.updated_at
+FROM oauth_consents c
+INNER JOIN oauth_clients cl ON cl.id = c.client_id
+WHERE c.user_id = $1
+ORDER BY c.updated_at DESC;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
@@ -0,0 +1,15 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: ConsumePasswordResetToken :one
+-- now comes from Go, like expires_at, so both use the same clock.
+UPDATE password_reset_tokens
+SET used_at = sqlc.arg('now')
+WHERE token_hash = sqlc.arg('token_hash') AND used_at IS NULL AND expires_at > sqlc.arg('now')
+RETURNING user_id;
@@ -0,0 +1,30 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPersonalAccessTokens :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT t.* FROM personal_access_tokens t
+INNER JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = sqlc.arg('token_hash')
+  AND t.revoked_at IS NULL
+  AND (t.expires_at IS NULL OR t.expires_at > sqlc.arg('now')::timestamp)
+  AND u.deleted_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+-- last_used_at is only rewritten once a minute, so bots reading in a loop
+-- don't turn every request into a write.
+UPDATE personal_access_tokens
+SET last_used_at = sqlc.arg('now')::timestamp
+WHERE id = sqlc.arg('id')
+AND (last_used_at IS NULL OR last_used_at < sqlc.arg('now')::timestamp - interval '1 minute');
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
@@ -0,0 +1,15 @@
+-- name: LockRateLimitBucket :one
+-- Creates the bucket if it is missing and, either way, locks its row until
+-- the transaction ends.
+INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+RETURNING *;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET tokens = $2, updated_at = $3
+WHERE key = $1;
+
+-- name: DeleteStaleRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets WHERE updated_at < $1;
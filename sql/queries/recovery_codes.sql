@@ -0,0 +1,11 @@
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
@@ -1,13 +1,43 @@
 -- name: CreateRefreshToken :exec
-INSERT INTO refresh_tokens (token, user_id, expires_at)
-VALUES ( $1, $2, $3);
+INSERT INTO refresh_tokens (token, user_id, expires_at, family_id, parent_token, user_agent, ip_address, client_id, scopes)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9);
 
--- name: GetUserByRefreshToken :one
-SELECT u.* FROM refresh_tokens t
-INNER JOIN users u ON u.id = t.user_id
-WHERE t.token = $1;
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens WHERE token = $1;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $2, updated_at = $3
 WHERE token = $1;
+
+-- name: ConsumeRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: GetSessionsByUserID :many
+SELECT t.family_id, t.user_agent, t.ip_address, t.last_used_at,
+CAST((SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) AS TIMESTAMP) AS started_at
+FROM refresh_tokens t
+WHERE t.user_id = sqlc.arg('user_id') AND t.revoked_at IS NULL AND t.expires_at > sqlc.arg('now')
+ORDER BY t.last_used_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeClientRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL;
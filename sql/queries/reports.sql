@@ -0,0 +1,26 @@
+-- name: CreateReport :one
+INSERT INTO reports (chirp_id, reporter_id, reason, details)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetReports :many
+-- The queue shows hidden chirps too, since reviewing them is the point.
+SELECT sqlc.embed(reports), sqlc.embed(chirps)
+FROM reports
+INNER JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = sqlc.arg('status')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+  OR (reports.created_at, reports.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY reports.created_at DESC, reports.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CloseReport :one
+UPDATE reports
+SET status = sqlc.arg('status'), closed_at = NOW()
+WHERE id = sqlc.arg('id') AND status = 'open'
+RETURNING *;
+
+-- name: ResolveChirpReports :exec
+UPDATE reports
+SET status = 'resolved', closed_at = NOW()
+WHERE chirp_id = $1 AND status = 'open';
@@ -1,7 +1,7 @@
 -- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
-VALUES ($1, $2)
-RETURNING id, email, created_at, updated_at, is_chirpy_red;
+INSERT INTO users (email, hashed_password, handle)
+VALUES ($1, $2, $3)
+RETURNING *;
 
 -- name: DeleteUsers :exec
 DELETE FROM users;
@@ -11,10 +11,92 @@ SELECT * FROM users WHERE email = $1;
 
 -- name: UpdateUser :one
 UPDATE users
-SET email = $2, hashed_password = $3, updated_at = $4
-WHERE id = $1 RETURNING *;
+SET email_verified_at = CASE
+  WHEN sqlc.narg('email')::text IS NULL OR sqlc.narg('email') = email THEN email_verified_at
+END,
+email = COALESCE(sqlc.narg('email'), email),
+hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+handle = COALESCE(sqlc.narg('handle'), handle),
+display_name = COALESCE(sqlc.narg('display_name'), display_name),
+bio = COALESCE(sqlc.narg('bio'), bio),
+updated_at = NOW()
+WHERE id = sqlc.arg('id') RETURNING *;
 
 -- name: SetUserChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = $2
 WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserProfileByHandle :one
+-- chirp_count only counts the user's own visible chirps; hidden chirps and rechirps are left out.
+SELECT u.id, u.handle, u.display_name, u.bio, u.created_at, u.is_chirpy_red,
+(SELECT COUNT(*) FROM chirps c
+  WHERE c.user_id = u.id AND c.hidden_at IS NULL AND c.rechirp_of_id IS NULL) AS chirp_count,
+(SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) AS follower_count,
+(SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id) AS following_count
+FROM users u
+WHERE LOWER(u.handle) = LOWER(sqlc.arg('handle')) AND u.deleted_at IS NULL;
+
+-- name: VerifyUserEmail :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2;
+
+-- name: SoftDeleteUser :exec
+-- deleted_at comes from Go, in UTC like the cutoff PurgeDeletedUsers is given.
+UPDATE users
+SET deleted_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < sqlc.arg('before')::timestamp;
+
+-- name: GetUserForLogin :one
+-- Unlike GetUserByID this includes accounts pending deletion, since logging
+-- in restores them.
+SELECT * FROM users WHERE id = $1;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg('step')::bigint
+WHERE id = sqlc.arg('id') AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg('step')::bigint);
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), suspended_until = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NOT NULL;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
@@ -0,0 +1,19 @@
+-- +goose up
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id uuid,
+ADD COLUMN parent_token TEXT;
+
+UPDATE refresh_tokens SET family_id = gen_random_uuid()
+WHERE family_id IS NULL;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose down
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN parent_token,
+DROP COLUMN family_id;
@@ -0,0 +1,12 @@
+-- +goose up
+CREATE TABLE chirp_likes (
+  user_id uuid NOT NULL,
+  chirp_id uuid NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (chirp_id, user_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+-- +goose down
+DROP TABLE chirp_likes;
@@ -0,0 +1,13 @@
+-- +goose up
+-- Deleting a chirp detaches its replies instead of removing them, so a
+-- conversation never loses chirps written by other users.
+ALTER TABLE chirps
+ADD COLUMN reply_to_id uuid REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_reply_to_id_created_at_id_idx ON chirps (reply_to_id, created_at, id);
+
+-- +goose down
+DROP INDEX chirps_reply_to_id_created_at_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN reply_to_id;
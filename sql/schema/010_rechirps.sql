@@ -0,0 +1,21 @@
+-- +goose up
+-- A rechirp is a chirp with an empty body that points at the chirp it
+-- reposts and disappears along with it. A quote chirp keeps its own body
+-- and only loses the reference when the quoted chirp is deleted.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of_id uuid REFERENCES chirps(id) ON DELETE CASCADE,
+ADD COLUMN quoted_chirp_id uuid REFERENCES chirps(id) ON DELETE SET NULL,
+ADD CONSTRAINT chirps_rechirp_check CHECK (
+  rechirp_of_id IS NULL OR (quoted_chirp_id IS NULL AND reply_to_id IS NULL)
+);
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_idx ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+
+-- +goose down
+DROP INDEX chirps_user_id_rechirp_of_id_idx;
+
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_rechirp_check,
+DROP COLUMN quoted_chirp_id,
+DROP COLUMN rechirp_of_id;
@@ -0,0 +1,17 @@
+-- +goose up
+-- IF NOT EXISTS because databases migrated before this moved here already
+-- have the handle from 012
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS handle TEXT,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_handle_idx ON users (LOWER(handle));
+
+-- +goose down
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
@@ -0,0 +1,10 @@
+-- +goose up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP;
+
+-- accounts created before verification existed keep posting rights
+UPDATE users SET email_verified_at = created_at;
+
+-- +goose down
+ALTER TABLE users
+DROP COLUMN email_verified_at;
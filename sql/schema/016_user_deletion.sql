@@ -0,0 +1,14 @@
+-- +goose up
+-- Deleted accounts are kept for a grace period before a background job
+-- removes them, cascading to everything they own.
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX users_deleted_at_idx ON users (deleted_at)
+WHERE deleted_at IS NOT NULL;
+
+-- +goose down
+DROP INDEX users_deleted_at_idx;
+
+ALTER TABLE users
+DROP COLUMN deleted_at;
@@ -0,0 +1,24 @@
+-- +goose up
+-- totp_secret is set when enrollment starts and totp_enabled_at once the
+-- user confirms it with a code. totp_last_step stops a code being replayed.
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled_at TIMESTAMP,
+ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE recovery_codes (
+  code_hash TEXT NOT NULL,
+  user_id uuid NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, code_hash),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_secret,
+DROP COLUMN totp_enabled_at,
+DROP COLUMN totp_last_step;
@@ -0,0 +1,54 @@
+-- +goose up
+-- Public clients have no secret and rely on PKCE alone.
+CREATE TABLE oauth_clients (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id uuid NOT NULL,
+  name TEXT NOT NULL,
+  redirect_uris TEXT[] NOT NULL,
+  secret_hash TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+CREATE TABLE oauth_authorization_codes (
+  code_hash TEXT PRIMARY KEY,
+  client_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  code_challenge TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_consents (
+  user_id uuid NOT NULL,
+  client_id uuid NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, client_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE
+);
+
+-- Refresh tokens issued to a client carry the scopes the user granted it.
+-- First-party sessions leave both columns null.
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id uuid REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[];
+
+-- +goose down
+ALTER TABLE refresh_tokens
+DROP COLUMN scopes,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_consents;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_clients;
@@ -0,0 +1,25 @@
+-- +goose up
+-- Failed logins are counted per email address and per client IP, keyed as
+-- "account:<email>" and "ip:<address>".
+CREATE TABLE login_throttles (
+  key TEXT PRIMARY KEY,
+  failures INTEGER NOT NULL,
+  last_failure_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_throttles_last_failure_at_idx ON login_throttles (last_failure_at);
+
+CREATE TABLE audit_log (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  ip_address TEXT NOT NULL,
+  detail TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_log_user_id_idx ON audit_log (user_id, created_at);
+
+-- +goose down
+DROP TABLE audit_log;
+DROP TABLE login_throttles;
@@ -0,0 +1,11 @@
+-- +goose up
+CREATE TABLE rate_limit_buckets (
+  key TEXT PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
+
+-- +goose down
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,35 @@
+-- +goose up
+CREATE TABLE moderation_lists (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL UNIQUE,
+  action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag')),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE moderation_words (
+  list_id uuid NOT NULL REFERENCES moderation_lists(id) ON DELETE CASCADE,
+  word TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (list_id, word)
+);
+
+-- chirps that matched a "flag" list, waiting for review
+CREATE TABLE chirp_flags (
+  chirp_id uuid PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  words TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the words that used to be hardcoded
+WITH list AS (
+  INSERT INTO moderation_lists (name, action) VALUES ('default', 'mask')
+  RETURNING id
+)
+INSERT INTO moderation_words (list_id, word)
+SELECT id, unnest(ARRAY['kerfuffle', 'sharbert', 'fornax']) FROM list;
+
+-- +goose down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_words;
+DROP TABLE moderation_lists;
@@ -0,0 +1,32 @@
+-- +goose up
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+-- a suspension without an end lasts until it is lifted
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP,
+ADD COLUMN suspended_until TIMESTAMP;
+
+CREATE TABLE reports (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  chirp_id uuid NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reporter_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'other')),
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  closed_at TIMESTAMP,
+  UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+
+-- +goose down
+DROP TABLE reports;
+
+ALTER TABLE users
+DROP COLUMN suspended_until,
+DROP COLUMN suspended_at;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
@@ -0,0 +1,23 @@
+-- +goose up
+CREATE TABLE blocks (
+  blocker_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  blocked_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (blocker_id, blocked_id),
+  CHECK (blocker_id <> blocked_id)
+);
+
+-- chirp queries check blocks in both directions
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id, blocker_id);
+
+CREATE TABLE mutes (
+  muter_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  muted_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (muter_id, muted_id),
+  CHECK (muter_id <> muted_id)
+);
+
+-- +goose down
+DROP TABLE mutes;
+DROP TABLE blocks;